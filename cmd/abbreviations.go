@@ -0,0 +1,173 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+
+	"github.com/goccy/go-yaml"
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/spf13/cobra"
+)
+
+// abbreviationsCmd represents the abbreviations command
+var abbreviationsCmd = &cobra.Command{
+	Use:   "abbreviations",
+	Short: "Manages the abbreviations decoding team names into titles",
+}
+
+// abbreviationsInitCmd represents the abbreviations init command
+var abbreviationsInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Generates an abbreviations file from the teams of the current schedule",
+	Long: `Scrapes the Lectio schedule and writes an abbreviations file with the subject codes of the teams found in it.
+Known subject codes are given their title, and the titles of unknown subject codes are left empty to be filled in.
+With --learnTeams, the titles of teams with unknown subject codes are looked up on the pages of the teams in Lectio.
+
+The file is written to the path of --abbreviations, unless another path is given with -o.
+
+Examples:
+
+	lego abbreviations init -u username -s 123
+	lego abbreviations init -u username -s 123 -w 4 --learnTeams
+	lego abbreviations init --snapshot before.json -o -`,
+	Run: func(cmd *cobra.Command, args []string) {
+		output, _ := cmd.Flags().GetString("output")
+		force, _ := cmd.Flags().GetBool("force")
+		snapshotPath, _ := cmd.Flags().GetString("snapshot")
+		opts := syncOptionsFromFlags(cmd)
+		if output == "" {
+			output = opts.abbreviations
+		}
+
+		if output != "-" && !force {
+			_, err := os.Stat(output)
+			if err == nil {
+				fatal("The abbreviations file already exists (use --force to overwrite it)", "path", output)
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				fatal("Could not check the abbreviations file", "path", output, "error", err)
+			}
+		}
+
+		abbreviations := &lectigo.Abbreviations{}
+		var modules map[string]lectigo.Module
+		if snapshotPath != "" {
+			snapshot, err := lectigo.LoadSnapshot(snapshotPath)
+			if err != nil {
+				fatal("Could not load snapshot", "error", err)
+			}
+			modules = snapshot.Modules
+		} else {
+			modules = scrapeTeams(cmd, opts, abbreviations)
+		}
+
+		var teams []string
+		for _, m := range modules {
+			for _, team := range m.Teams {
+				if !slices.Contains(teams, team) {
+					teams = append(teams, team)
+				}
+			}
+		}
+		slices.Sort(teams)
+
+		err := writeOutput(output, func(w io.Writer) error {
+			return writeStarterAbbreviations(w, abbreviations, teams)
+		})
+		if err != nil {
+			fatal("Could not write abbreviations", "error", err)
+		}
+		if output != "-" {
+			fmt.Printf("Wrote the abbreviations of %v teams to %s\n", len(teams), output)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(abbreviationsCmd)
+	abbreviationsCmd.AddCommand(abbreviationsInitCmd)
+
+	addLectioFlags(abbreviationsInitCmd)
+	abbreviationsInitCmd.Flags().StringP("output", "o", "", "The path to write the abbreviations to, or - for stdout (default is the path of --abbreviations)")
+	abbreviationsInitCmd.Flags().Bool("force", false, "Overwrite an existing abbreviations file")
+	abbreviationsInitCmd.Flags().String("snapshot", "", "Use the teams of a snapshot instead of scraping Lectio")
+}
+
+// Scrapes the modules of the weeks of the options. With --learnTeams, the titles of the teams are learned into the
+// abbreviations
+func scrapeTeams(cmd *cobra.Command, opts syncOptions, abbreviations *lectigo.Abbreviations) map[string]lectigo.Module {
+	account := lectioAccountFromFlags(cmd)
+
+	// The abbreviations file is being generated, so it is not read
+	opts.decodeClass = false
+	l, err := newLectio(account, opts, accountLogger(account))
+	if err != nil {
+		fatal("Could not start Lectio", "error", err)
+	}
+	defer l.Cancel()
+
+	modules, err := l.GetScheduleWeeks(opts.weeks, account.target())
+	if err != nil {
+		fatal("Could not get Lectio schedule", "error", err)
+	}
+
+	if opts.learnTeams {
+		l.Abbreviations = abbreviations
+		err = learnTeamTitles(l, modules, opts)
+		if err != nil {
+			fatal("Could not learn team titles", "error", err)
+		}
+	}
+	return modules
+}
+
+// Writes an abbreviations file with the subject codes of the teams. Teams with an unknown subject code are listed by
+// themselves when their title has been learned
+func writeStarterAbbreviations(w io.Writer, abbreviations *lectigo.Abbreviations, teams []string) error {
+	titles := make(map[string]string)
+	learned := yaml.MapSlice{}
+	for _, team := range teams {
+		name := abbreviations.ParseTeam(team)
+		title, known := lectigo.DefaultSubjects[name.Subject]
+		if !known {
+			if title, ok := abbreviations.Decode(team); ok {
+				learned = append(learned, yaml.MapItem{Key: team, Value: title})
+				continue
+			}
+		}
+		if name.Subject != "" {
+			titles[name.Subject] = title
+		}
+	}
+
+	codes := make([]string, 0, len(titles))
+	for code := range titles {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	subjects := yaml.MapSlice{}
+	for _, code := range codes {
+		subjects = append(subjects, yaml.MapItem{Key: code, Value: titles[code]})
+	}
+
+	file := yaml.MapSlice{{Key: "subjects", Value: subjects}}
+	if len(learned) > 0 {
+		file = append(file, yaml.MapItem{Key: "teams", Value: learned})
+	}
+	b, err := yaml.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "# Subject codes decode every team of the subject, eg. MA decodes \"2a MA\" and \"3g MAA\".")
+	fmt.Fprintln(w, "# Teams listed under teams are decoded before their subject code. Fill in the empty titles.")
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,238 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/spf13/cobra"
+)
+
+// A Lectio account and the Google Calendar its schedule is synced to
+type accountConfig struct {
+	Name         string `yaml:"name"`         // The name of the account, used in the summary
+	Username     string `yaml:"username"`     // Lectio username
+	Password     string `yaml:"password"`     // Lectio password. Prefer passwordFile, passwordEnv or the secret store
+	PasswordFile string `yaml:"passwordFile"` // Path to a file holding the Lectio password
+	PasswordEnv  string `yaml:"passwordEnv"`  // Name of the environment variable holding the Lectio password
+	SchoolID     string `yaml:"schoolID"`     // Lectio school ID or school name
+	Cookies      string `yaml:"cookies"`      // Path to exported Lectio cookies, used instead of the password
+	SessionPath  string `yaml:"sessionPath"`  // Path to the session file of the account. Defaults to session-<name>.json
+	CalendarID   string `yaml:"calendarID"`   // Google Calendar calendar ID
+	TokenPath    string `yaml:"tokenPath"`    // Path to the Google OAuth token file. Defaults to token.json
+	Namespace    string `yaml:"namespace"`    // Separates the events of accounts sharing a calendar
+	Student      string `yaml:"student"`      // Lectio ID of a student whose schedule is synced instead
+	Teacher      string `yaml:"teacher"`      // Lectio ID of a teacher whose schedule is synced instead
+	Room         string `yaml:"room"`         // Lectio ID of a room whose schedule is synced instead
+	Class        string `yaml:"class"`        // Lectio ID of a class whose schedule is synced instead
+}
+
+// The outcome of syncing a single account
+type accountResult struct {
+	account  accountConfig
+	result   *lectigo.SyncResult
+	err      error
+	phases   map[syncPhase]time.Duration // The time taken by each phase of the sync
+	warnings []string                    // The warnings and errors logged by the sync
+}
+
+// Returns the schedule to sync for the account
+func (a accountConfig) target() lectigo.ScheduleTarget {
+	switch {
+	case a.Student != "":
+		return lectigo.ScheduleTarget{Type: lectigo.ScheduleStudent, ID: a.Student}
+	case a.Teacher != "":
+		return lectigo.ScheduleTarget{Type: lectigo.ScheduleTeacher, ID: a.Teacher}
+	case a.Room != "":
+		return lectigo.ScheduleTarget{Type: lectigo.ScheduleRoom, ID: a.Room}
+	case a.Class != "":
+		return lectigo.ScheduleTarget{Type: lectigo.ScheduleClass, ID: a.Class}
+	}
+	return lectigo.ScheduleTarget{Type: lectigo.ScheduleSelf}
+}
+
+// Reads the accounts file, filling in defaults and checking that accounts sharing a calendar are namespaced
+func loadAccounts(path string) ([]accountConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []accountConfig
+	err = yaml.Unmarshal(b, &accounts)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse accounts file %q: %w", path, err)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("accounts file %q contains no accounts", path)
+	}
+
+	names := make(map[string]bool)
+	calendars := make(map[string][]accountConfig)
+	for i := range accounts {
+		account := &accounts[i]
+		if account.Name == "" {
+			account.Name = account.Username
+		}
+		if account.Name == "" {
+			return nil, fmt.Errorf("account %d has neither a name nor a username", i+1)
+		}
+		if names[account.Name] {
+			return nil, fmt.Errorf("account name %q is used more than once", account.Name)
+		}
+		names[account.Name] = true
+
+		if account.SchoolID == "" {
+			return nil, fmt.Errorf("account %q has no schoolID", account.Name)
+		}
+		if account.Cookies == "" && account.Username == "" {
+			return nil, fmt.Errorf("account %q needs either a username or cookies", account.Name)
+		}
+		if account.CalendarID == "" {
+			account.CalendarID = "primary"
+		}
+		if account.TokenPath == "" {
+			account.TokenPath = "token.json"
+		}
+		if account.SessionPath == "" {
+			account.SessionPath = fmt.Sprintf("session-%s.json", account.Name)
+		}
+
+		key := account.TokenPath + "\x00" + account.CalendarID
+		calendars[key] = append(calendars[key], *account)
+	}
+
+	// Without namespaces, accounts sharing a calendar would delete the events of each other
+	for _, shared := range calendars {
+		if len(shared) < 2 {
+			continue
+		}
+		namespaces := make(map[string]bool)
+		for _, account := range shared {
+			if account.Namespace == "" || namespaces[account.Namespace] {
+				return nil, fmt.Errorf("account %q shares calendar %q with other accounts and needs a unique namespace", account.Name, account.CalendarID)
+			}
+			namespaces[account.Namespace] = true
+		}
+	}
+
+	return accounts, nil
+}
+
+// Syncs all accounts of the accounts file concurrently and prints a combined summary. A failing account does not
+// stop the others
+func syncAccounts(cmd *cobra.Command, path string, opts syncOptions) {
+	syncers := prepareAccounts(cmd, path, opts)
+	startedAt := time.Now()
+
+	slog.Info("Syncing Lectio accounts with Google Calendar", "accounts", len(syncers))
+
+	results := syncAll(syncers)
+	closeSyncers(syncers)
+
+	if !reportToStdout(cmd) {
+		printAccountResults(results)
+	}
+	finishSync(cmd, startedAt, results)
+}
+
+// Reads the accounts file, resolves the school and password of every account and creates their Google Calendar clients
+func prepareAccounts(cmd *cobra.Command, path string, opts syncOptions) []*accountSyncer {
+	accounts, err := loadAccounts(path)
+	if err != nil {
+		fatal("Could not load accounts", "error", err)
+	}
+
+	for i := range accounts {
+		accounts[i].SchoolID, err = resolveSchoolID(cmd, accounts[i].SchoolID)
+		if err != nil {
+			fatal("Could not find school", "account", accounts[i].Name, "error", err)
+		}
+		err = resolvePassword(cmd, &accounts[i])
+		if err != nil {
+			fatal("Could not get Lectio password", "account", accounts[i].Name, "error", err)
+		}
+	}
+
+	// OAuth clients are created up front, as a missing token starts an interactive login on a fixed port
+	clients := make(map[string]*http.Client)
+	for _, account := range accounts {
+		if _, ok := clients[account.TokenPath]; ok {
+			continue
+		}
+		client, err := newGoogleClient(cmd, opts.credentialsPath, account.TokenPath)
+		if err != nil {
+			exitWith(exitCalendarAuthFailed, "Could not get Google Calendar client", "tokenPath", account.TokenPath, "error", err)
+		}
+		clients[account.TokenPath] = client
+	}
+
+	syncers := make([]*accountSyncer, len(accounts))
+	for i, account := range accounts {
+		// Each account has its own activity and team caches, so concurrent accounts do not overwrite each other's
+		accountOpts := opts
+		accountOpts.detailsCachePath = accountCachePath(opts.detailsCachePath, account)
+		accountOpts.teamCachePath = accountCachePath(opts.teamCachePath, account)
+		syncers[i] = newAccountSyncer(account, clients[account.TokenPath], accountOpts)
+	}
+	return syncers
+}
+
+// Returns the path of the cache of the account, eg. "teamcache-anna.json" for "teamcache.json"
+func accountCachePath(path string, account accountConfig) string {
+	return fmt.Sprintf("%s-%s.json", strings.TrimSuffix(path, ".json"), account.Name)
+}
+
+// Syncs the accounts concurrently and returns the outcome of each
+func syncAll(syncers []*accountSyncer) []accountResult {
+	results := make([]accountResult, len(syncers))
+	var wg sync.WaitGroup
+	for i, s := range syncers {
+		wg.Add(1)
+		go func(i int, s *accountSyncer) {
+			defer wg.Done()
+			results[i] = s.run()
+		}(i, s)
+	}
+	wg.Wait()
+	return results
+}
+
+// Ends the browser instances of the accounts
+func closeSyncers(syncers []*accountSyncer) {
+	for _, s := range syncers {
+		s.close()
+	}
+}
+
+// Prints the combined summary of the accounts
+func printAccountResults(results []accountResult) {
+	var total lectigo.SyncResult
+
+	fmt.Println("\nRESULTS ==============================")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACCOUNT\tUPDATED\tINSERTED\tDELETED\tTIME\tERROR")
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t%s\n", r.account.Name, strings.ReplaceAll(r.err.Error(), "\n", " "))
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%v\t%v\t%v\t%v\t\n", r.account.Name, r.result.Updated, r.result.Inserted, r.result.Deleted, r.result.Duration)
+		total.Updated += r.result.Updated
+		total.Inserted += r.result.Inserted
+		total.Deleted += r.result.Deleted
+	}
+	fmt.Fprintf(w, "TOTAL\t%v\t%v\t%v\t\t\n", total.Updated, total.Inserted, total.Deleted)
+	w.Flush()
+	fmt.Println("======================================")
+}
@@ -4,14 +4,8 @@ Copyright © 2023 Mattis Møl Kristensen <mattismoel@gmail.com>
 package cmd
 
 import (
-	"log"
-	"os"
-	"strings"
-
 	"github.com/mattismoel/lectigo/pkg/lectigo"
-	"github.com/mattismoel/lectigo/util"
 	"github.com/spf13/cobra"
-	"golang.org/x/oauth2/google"
 )
 
 // clearCmd represents the clear command
@@ -23,40 +17,36 @@ var clearCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		calendarID, err := cmd.Flags().GetString("calendarID")
 		if err != nil {
-			log.Fatalf("Could not get calendar ID: %v\n", err)
+			fatal("Could not get calendar ID", "error", err)
 		}
 		tokenPath, err := cmd.Flags().GetString("token")
 		if err != nil {
-			log.Fatalf("Could not get token: %v\n", err)
+			fatal("Could not get token", "error", err)
 		}
-		// Reads the credentials file and creates a config from it - this is used to create the client
-		bytes, err := os.ReadFile("credentials.json")
+		namespace, err := cmd.Flags().GetString("namespace")
 		if err != nil {
-			log.Fatalf("Could not read contents of credentials.json: %v\n", err)
+			fatal("Could not get namespace", "error", err)
 		}
-
-		config, err := google.ConfigFromJSON(bytes, "https://www.googleapis.com/auth/calendar.calendarlist.readonly", "https://www.googleapis.com/auth/calendar.events")
+		credentialsPath, err := cmd.Flags().GetString("credentials")
 		if err != nil {
-			log.Fatalf("Could not create config from credentials.json")
+			fatal("Could not get credentials", "error", err)
 		}
 
-		if !strings.HasSuffix(tokenPath, ".json") {
-			tokenPath += ".json"
-		}
-
-		client, err := util.GetClient(config, tokenPath)
+		client, err := newGoogleClient(cmd, credentialsPath, tokenPath)
 		if err != nil {
-			log.Fatalf("Could not get Google Calendar client: %v\n", err)
+			fatal("Could not get Google Calendar client", "error", err)
 		}
 
 		c, err := lectigo.NewGoogleCalendar(client, calendarID)
 		if err != nil {
-			log.Fatalf("Could not create Google Calendar instance: %v\n", err)
+			fatal("Could not create Google Calendar instance", "error", err)
 		}
 
+		c.Namespace = namespace
+
 		err = c.Clear()
 		if err != nil {
-			log.Fatalf("Could not clear Google Calendar: %v\n", err)
+			fatal("Could not clear Google Calendar", "error", err)
 		}
 	},
 }
@@ -66,6 +56,8 @@ func init() {
 
 	clearCmd.Flags().StringP("calendarID", "c", "primary", "The Google Calendar ID")
 	clearCmd.Flags().StringP("token", "t", "token.json", "The OAuth token file for Google Calendar")
+	clearCmd.Flags().String("credentials", "credentials.json", "The path to the Google OAuth client credentials")
+	clearCmd.Flags().String("namespace", "", "Only clear the events of the account with this namespace in a shared calendar")
 
 	// Here you will define your flags and configuration settings.
 
@@ -0,0 +1,321 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// The profile name used when neither --profile nor defaultProfile is given
+const defaultProfileName = "default"
+
+// Flags holding file paths. Relative paths in a profile are resolved against the directory of the config file
+var configPathKeys = map[string]bool{
+	"abbreviations": true,
+	"accounts":      true,
+	"blacklist":     true,
+	"cache":         true,
+	"cookies":       true,
+	"credentials":   true,
+	"detailsCache":  true,
+	"filters":       true,
+	"notifications": true,
+	"passwordFile":  true,
+	"scheduleCache": true,
+	"secrets":       true,
+	"sessionPath":   true,
+	"state":         true,
+	"teamCache":     true,
+	"token":         true,
+	"tokenPath":     true,
+	"transforms":    true,
+}
+
+// The configuration file holding the named profiles
+type config struct {
+	DefaultProfile string                    `yaml:"defaultProfile"` // The profile used when --profile is not given
+	Profiles       map[string]map[string]any `yaml:"profiles"`       // Profile name to flag values
+}
+
+// The profile selected for the current command
+type profile struct {
+	name      string
+	dir       string                     // The directory of the config file
+	values    map[string]any             // Flag name to value
+	blacklist *[]lectigo.ClassesToIgnore // Classes to ignore given inline instead of as a path
+	filter    *lectigo.Filter            // Filter rules given inline instead of as a path
+	transform *lectigo.Transform         // Transform rules given inline instead of as a path
+}
+
+var activeProfile profile
+
+// Returns the default path of the config file in the user config directory (eg. ~/.config/lectigo/config.yml)
+func defaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lectigo", "config.yml"), nil
+}
+
+// Reads the config file at path
+func loadConfig(path string) (*config, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &config{}
+	err = yaml.Unmarshal(bytes, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse config file %q: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Loads the config file given by --config and applies the selected profile to the flags of cmd.
+// Flags given on the command line take precedence over the profile
+func applyConfig(cmd *cobra.Command) error {
+	configPath, _ := cmd.Flags().GetString("config")
+	profileName, _ := cmd.Flags().GetString("profile")
+
+	explicit := configPath != ""
+	if !explicit {
+		var err error
+		configPath, err = defaultConfigPath()
+		if err != nil {
+			// Without a config directory there is no default config file to read
+			if profileName != "" {
+				return fmt.Errorf("could not find config directory: %w", err)
+			}
+			return nil
+		}
+	}
+
+	cfg, err := loadConfig(configPath)
+	if errors.Is(err, fs.ErrNotExist) && !explicit && profileName == "" {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not load config: %w", err)
+	}
+
+	if profileName == "" {
+		profileName = cfg.DefaultProfile
+	}
+	if profileName == "" {
+		if _, ok := cfg.Profiles[defaultProfileName]; !ok {
+			return nil
+		}
+		profileName = defaultProfileName
+	}
+
+	values, ok := cfg.Profiles[profileName]
+	if !ok {
+		return fmt.Errorf("profile %q does not exist in %s", profileName, configPath)
+	}
+
+	activeProfile = profile{
+		name:   profileName,
+		dir:    filepath.Dir(configPath),
+		values: values,
+	}
+	return activeProfile.apply(cmd)
+}
+
+// Sets the flags of cmd not given on the command line to the values of the profile
+func (p *profile) apply(cmd *cobra.Command) error {
+	known := allFlagNames(cmd.Root())
+
+	keys := make([]string, 0, len(p.values))
+	for key := range p.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := p.values[key]
+
+		if key == "blacklist" {
+			if rules, ok := value.([]any); ok {
+				blacklist, err := parseInlineBlacklist(rules)
+				if err != nil {
+					return fmt.Errorf("profile %q: %w", p.name, err)
+				}
+				p.blacklist = blacklist
+				continue
+			}
+		}
+
+		if key == "filters" {
+			if rules, ok := value.([]any); ok {
+				filter, err := parseInlineFilter(rules)
+				if err != nil {
+					return fmt.Errorf("profile %q: %w", p.name, err)
+				}
+				p.filter = filter
+				continue
+			}
+		}
+
+		if key == "transforms" {
+			if rules, ok := value.([]any); ok {
+				transform, err := parseInlineTransform(rules)
+				if err != nil {
+					return fmt.Errorf("profile %q: %w", p.name, err)
+				}
+				p.transform = transform
+				continue
+			}
+		}
+
+		if !known[key] {
+			return fmt.Errorf("profile %q: unknown option %q", p.name, key)
+		}
+
+		flag := cmd.Flags().Lookup(key)
+		if flag == nil || flag.Changed {
+			// The option belongs to another command or is overridden on the command line
+			continue
+		}
+
+		values, err := p.flagValues(key, value)
+		if err != nil {
+			return fmt.Errorf("profile %q: %w", p.name, err)
+		}
+
+		// Setting the value directly leaves the flag unchanged, so flag groups only consider the command line
+		_, isList := value.([]any)
+		if slice, ok := flag.Value.(pflag.SliceValue); ok && isList {
+			err = slice.Replace(values)
+		} else {
+			err = flag.Value.Set(strings.Join(values, ","))
+		}
+		if err != nil {
+			return fmt.Errorf("profile %q: invalid value for %q: %w", p.name, key, err)
+		}
+	}
+	return nil
+}
+
+// Formats a profile value as flag values. A list gives a value per item, and relative paths are resolved
+func (p *profile) flagValues(key string, value any) ([]string, error) {
+	var values []string
+	switch v := value.(type) {
+	case nil:
+		return nil, fmt.Errorf("option %q has no value", key)
+	case []any:
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+	case map[string]any:
+		return nil, fmt.Errorf("option %q can not be a map", key)
+	default:
+		values = []string{fmt.Sprint(v)}
+	}
+
+	if configPathKeys[key] {
+		for i, str := range values {
+			if str == "" {
+				continue
+			}
+			str = expandHome(str)
+			if !filepath.IsAbs(str) {
+				str = filepath.Join(p.dir, str)
+			}
+			values[i] = str
+		}
+	}
+	return values, nil
+}
+
+// Converts the rules of an inline blacklist to classes to ignore
+func parseInlineBlacklist(rules []any) (*[]lectigo.ClassesToIgnore, error) {
+	bytes, err := yaml.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("could not read blacklist: %w", err)
+	}
+
+	blacklist := &[]lectigo.ClassesToIgnore{}
+	err = yaml.Unmarshal(bytes, blacklist)
+	if err != nil {
+		return nil, fmt.Errorf("could not read blacklist: %w", err)
+	}
+	_, err = lectigo.BlacklistRules(*blacklist)
+	if err != nil {
+		return nil, err
+	}
+	return blacklist, nil
+}
+
+// Converts the rules of an inline filter to a filter
+func parseInlineFilter(rules []any) (*lectigo.Filter, error) {
+	bytes, err := yaml.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("could not read filters: %w", err)
+	}
+
+	filter, err := lectigo.ParseFilter(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not read filters: %w", err)
+	}
+	return filter, nil
+}
+
+// Converts the rules of an inline transform to a transform
+func parseInlineTransform(rules []any) (*lectigo.Transform, error) {
+	bytes, err := yaml.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("could not read transforms: %w", err)
+	}
+
+	transform, err := lectigo.ParseTransform(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not read transforms: %w", err)
+	}
+	return transform, nil
+}
+
+// Returns the names of the flags of cmd and all of its subcommands
+func allFlagNames(cmd *cobra.Command) map[string]bool {
+	names := make(map[string]bool)
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		c.LocalFlags().VisitAll(func(f *pflag.Flag) { names[f.Name] = true })
+		c.PersistentFlags().VisitAll(func(f *pflag.Flag) { names[f.Name] = true })
+		for _, sub := range c.Commands() {
+			walk(sub)
+		}
+	}
+	walk(cmd)
+
+	// The config file can not select itself
+	delete(names, "config")
+	delete(names, "profile")
+	delete(names, "help")
+	return names
+}
+
+// Replaces a leading ~ with the home directory of the user
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
@@ -0,0 +1,124 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Compares two snapshots of a Lectio schedule",
+	Long: `Compares two snapshots saved by the snapshot command and prints the added, removed and changed modules. For changed
+modules every differing field is shown. Files written by an older lectigo with only the modules can be compared as well.
+
+Examples:
+
+	lego diff before.json after.json
+	lego diff before.json after.json -f json`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+
+		old, err := lectigo.LoadSnapshot(args[0])
+		if err != nil {
+			fatal("Could not load snapshot", "error", err)
+		}
+		new, err := lectigo.LoadSnapshot(args[1])
+		if err != nil {
+			fatal("Could not load snapshot", "error", err)
+		}
+		diff := lectigo.DiffSnapshots(old, new)
+
+		switch format {
+		case "text":
+			printSnapshotDiff(os.Stdout, old, new, diff)
+		case "json":
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(diff)
+			if err != nil {
+				fatal("Could not write diff", "error", err)
+			}
+		default:
+			fatal("Unknown format (use text or json)", "format", format)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringP("format", "f", "text", "The output format, text or json")
+}
+
+// Prints the differences between the snapshots in a human-readable form
+func printSnapshotDiff(w io.Writer, old, new *lectigo.Snapshot, diff *lectigo.SnapshotDiff) {
+	fmt.Fprintf(w, "--- %s\n", describeSnapshot(old))
+	fmt.Fprintf(w, "+++ %s\n", describeSnapshot(new))
+	if diff.Empty() {
+		fmt.Fprintln(w, "No differences")
+		return
+	}
+
+	if len(diff.Added) > 0 {
+		fmt.Fprintf(w, "\nADDED (%v)\n", len(diff.Added))
+		for _, m := range diff.Added {
+			fmt.Fprintf(w, "+ %s\n", describeModule(&m))
+		}
+	}
+	if len(diff.Removed) > 0 {
+		fmt.Fprintf(w, "\nREMOVED (%v)\n", len(diff.Removed))
+		for _, m := range diff.Removed {
+			fmt.Fprintf(w, "- %s\n", describeModule(&m))
+		}
+	}
+	if len(diff.Changed) > 0 {
+		fmt.Fprintf(w, "\nCHANGED (%v)\n", len(diff.Changed))
+		for _, changed := range diff.Changed {
+			fmt.Fprintf(w, "~ %s\n", describeModule(&changed.New))
+			for _, field := range changed.Fields {
+				fmt.Fprintf(w, "    %s: %s -> %s\n", field.Field, diffValue(field.Old), diffValue(field.New))
+			}
+		}
+	}
+}
+
+// Describes where and when the snapshot was taken
+func describeSnapshot(s *lectigo.Snapshot) string {
+	if s.ScrapedAt.IsZero() {
+		return fmt.Sprintf("%v modules", len(s.Modules))
+	}
+	description := fmt.Sprintf("school %s", s.SchoolID)
+	if s.Username != "" {
+		description += ", " + s.Username
+	}
+	return fmt.Sprintf("%s, scraped %s, %v modules", description, s.ScrapedAt.Local().Format("2006-01-02 15:04"), len(s.Modules))
+}
+
+func describeModule(m *lectigo.Module) string {
+	return fmt.Sprintf("%s, %s-%s (%s)", m.Title, formatHistoryTime(m.StartDate), m.EndDate.Format("15:04"), m.Id)
+}
+
+// Quotes a field value for a single line, shortening long values like notes
+func diffValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	if runes := []rune(value); len(runes) > 60 {
+		value = string(runes[:57]) + "..."
+	}
+	if strings.ContainsAny(value, " \n\t") {
+		return fmt.Sprintf("%q", value)
+	}
+	return value
+}
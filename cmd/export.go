@@ -0,0 +1,104 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattismoel/lectigo/pkg/export"
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/spf13/cobra"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports the Lectio schedule in a given format",
+	Long: `Scrapes the Lectio schedule and writes it in a given format, to a file or to stdout. Available formats are:
+
+` + strings.Join(export.ModuleFormats(), ", ") + `
+
+With --snapshot, the modules of a snapshot saved by the snapshot command are exported instead of scraping Lectio.
+
+Examples:
+
+	lego export -u username -s 123 -f ical -o schedule.ics
+	lego export -u username -s 123 -w 1 -f markdown
+	lego export --snapshot before.json -f csv -o before.csv`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		snapshotPath, _ := cmd.Flags().GetString("snapshot")
+
+		// Fail on an unknown format before logging in to Lectio
+		f, err := export.Lookup(format)
+		if err == nil && f.Modules == nil {
+			err = fmt.Errorf("format %q does not support modules", format)
+		}
+		if err != nil {
+			fatal("Unsupported format", "error", err, "formats", strings.Join(export.ModuleFormats(), ", "))
+		}
+
+		var modules map[string]lectigo.Module
+		if snapshotPath != "" {
+			snapshot, err := lectigo.LoadSnapshot(snapshotPath)
+			if err != nil {
+				fatal("Could not load snapshot", "error", err)
+			}
+			modules = snapshot.Modules
+		} else {
+			opts := syncOptionsFromFlags(cmd)
+			account := lectioAccountFromFlags(cmd)
+
+			l, err := newLectio(account, opts, accountLogger(account))
+			if err != nil {
+				fatal("Could not start Lectio", "error", err)
+			}
+			modules, err = scrapeModules(l, account, opts)
+			l.Cancel()
+			if err != nil {
+				fatal("Could not scrape Lectio", "error", err)
+			}
+		}
+
+		err = writeOutput(output, func(w io.Writer) error {
+			return f.Modules(w, lectigo.SortedModules(modules))
+		})
+		if err != nil {
+			fatal("Could not export modules", "error", err)
+		}
+		if output != "" && output != "-" {
+			fmt.Printf("Exported %v modules to %s\n", len(modules), output)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	addLectioFlags(exportCmd)
+
+	exportCmd.Flags().StringP("format", "f", "json", "The format to export in ("+strings.Join(export.ModuleFormats(), ", ")+")")
+	exportCmd.Flags().StringP("output", "o", "", "The path to write to. Leave empty or use - for stdout")
+	exportCmd.Flags().String("snapshot", "", "Export the modules of a snapshot instead of scraping Lectio")
+}
+
+// Calls write with the file at path, or with stdout if path is empty or "-"
+func writeOutput(path string, write func(w io.Writer) error) error {
+	if path == "" || path == "-" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,189 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+)
+
+// filtersCmd represents the filters command
+var filtersCmd = &cobra.Command{
+	Use:   "filters",
+	Short: "Manages the rules deciding which modules are synced",
+}
+
+// filtersTestCmd represents the filters test command
+var filtersTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Shows which modules of a week each filter rule would drop",
+	Long: `Scrapes a week of the Lectio schedule without dropping any modules, and shows which modules each rule of the blacklist
+and the filters would decide. Every module is decided by the first rule matching it, and modules matching no rule are kept.
+
+Examples:
+
+	lego filters test -u username -s 123 --filters filters.yml
+	lego filters test -u username -s 123 --filters filters.yml --week +1
+	lego filters test --snapshot before.json --filters filters.yml --week 43`,
+	Run: func(cmd *cobra.Command, args []string) {
+		weekValue, _ := cmd.Flags().GetString("week")
+		snapshotPath, _ := cmd.Flags().GetString("snapshot")
+		year, week, err := parseShowWeek(weekValue, time.Now())
+		if err != nil {
+			fatal("Could not parse week", "error", err)
+		}
+
+		opts := syncOptionsFromFlags(cmd)
+		rules, err := filterRules(opts)
+		if err != nil {
+			fatal("Could not load filter rules", "error", err)
+		}
+
+		var modules []scrapedModule
+		if snapshotPath != "" {
+			snapshot, err := lectigo.LoadSnapshot(snapshotPath)
+			if err != nil {
+				fatal("Could not load snapshot", "error", err)
+			}
+			monday := util.ISOWeekStart(year, week, scheduleLocation())
+			for _, m := range weekModules(snapshot.Modules, monday, monday.AddDate(0, 0, 7)) {
+				modules = append(modules, scrapedModule{module: m})
+			}
+		} else {
+			modules, err = scrapeUnfiltered(cmd, opts, year, week)
+			if err != nil {
+				fatal("Could not scrape Lectio", "error", err)
+			}
+		}
+
+		fmt.Printf("Week %v, %v\n", week, year)
+		printFilterTest(os.Stdout, rules, modules)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(filtersCmd)
+	filtersCmd.AddCommand(filtersTestCmd)
+
+	addLectioFlags(filtersTestCmd)
+	filtersTestCmd.Flags().String("week", "", "The week to test, eg. 43, 2026-W43 or +1 for next week (default is the current week)")
+	filtersTestCmd.Flags().String("snapshot", "", "Test the modules of a snapshot instead of scraping Lectio")
+}
+
+// A module with its raw title as shown in Lectio
+type scrapedModule struct {
+	module lectigo.Module
+	title  string
+}
+
+// Returns the rules of the blacklist followed by the filter rules of the options
+func filterRules(opts syncOptions) ([]lectigo.FilterRule, error) {
+	blacklist := opts.blacklist
+	if blacklist == nil {
+		var err error
+		blacklist, err = lectigo.LoadBlacklist(opts.blacklistPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rules, err := lectigo.BlacklistRules(*blacklist)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := opts.filter
+	if filter == nil && opts.filtersPath != "" {
+		filter, err = lectigo.LoadFilter(opts.filtersPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if filter != nil {
+		for i, rule := range filter.Rules {
+			// Rules are named by their position in the filters rather than after the blacklist
+			rule.Name = rule.DisplayName(i)
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// Scrapes every module of the week, including the modules the blacklist and filters drop
+func scrapeUnfiltered(cmd *cobra.Command, opts syncOptions, year, week int) ([]scrapedModule, error) {
+	account := lectioAccountFromFlags(cmd)
+	l, err := newLectio(account, opts, accountLogger(account))
+	if err != nil {
+		return nil, err
+	}
+	defer l.Cancel()
+
+	var mu sync.Mutex
+	scraped := make(map[string]scrapedModule)
+	l.Scraped = func(m lectigo.Module, title string) {
+		mu.Lock()
+		defer mu.Unlock()
+		scraped[m.Id] = scrapedModule{module: m, title: title}
+	}
+
+	// Day notes are never filtered, so only the modules passed to Scraped are tested
+	_, err = l.GetScheduleOfYear(year, week, account.target())
+	if err != nil {
+		return nil, fmt.Errorf("could not get Lectio schedule: %w", err)
+	}
+
+	modules := make(map[string]lectigo.Module, len(scraped))
+	for id, s := range scraped {
+		modules[id] = s.module
+	}
+	var sorted []scrapedModule
+	for _, m := range lectigo.SortedModules(modules) {
+		sorted = append(sorted, scraped[m.Id])
+	}
+	return sorted, nil
+}
+
+// Prints the modules decided by each rule, the rule being the first one matching them
+func printFilterTest(w io.Writer, rules []lectigo.FilterRule, modules []scrapedModule) {
+	filter := &lectigo.Filter{Rules: rules}
+	decided := make([][]lectigo.Module, len(rules))
+	dropped := 0
+	for _, s := range modules {
+		i := filter.Match(&s.module, s.title)
+		if i < 0 {
+			continue
+		}
+		decided[i] = append(decided[i], s.module)
+		if rules[i].Action == lectigo.FilterExclude {
+			dropped++
+		}
+	}
+	fmt.Fprintf(w, "%v modules, %v dropped\n", len(modules), dropped)
+
+	for i, rule := range rules {
+		fmt.Fprintf(w, "\n%s (%s): ", rule.DisplayName(i), rule.Action)
+		switch len(decided[i]) {
+		case 0:
+			fmt.Fprintln(w, "no modules")
+		case 1:
+			fmt.Fprintln(w, "1 module")
+		default:
+			fmt.Fprintf(w, "%v modules\n", len(decided[i]))
+		}
+
+		sign := "-"
+		if rule.Action == lectigo.FilterInclude {
+			sign = "+"
+		}
+		for _, m := range decided[i] {
+			fmt.Fprintf(w, "  %s %s\n", sign, describeModule(&m))
+		}
+	}
+}
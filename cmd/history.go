@@ -0,0 +1,201 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+// Describes the calendar actions in the history
+var actionVerbs = map[lectigo.ActionType]string{
+	lectigo.ActionInsert: "inserted",
+	lectigo.ActionUpdate: "updated",
+	lectigo.ActionDelete: "deleted",
+}
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Shows the history of syncs recorded in the state store",
+	Long: `Shows the history recorded in the state store by sync and watch. Without flags the latest runs are listed.
+With --module, every version of the module and every change made to its calendar event is shown. With --week, the
+same is shown for every module of the week.
+
+Examples:
+
+	lego history
+	lego history --module 61234567890
+	lego history --week 43 --year 2026`,
+	Run: func(cmd *cobra.Command, args []string) {
+		statePath, _ := cmd.Flags().GetString("state")
+		account, _ := cmd.Flags().GetString("account")
+		moduleID, _ := cmd.Flags().GetString("module")
+		weekValue, _ := cmd.Flags().GetString("week")
+		year, _ := cmd.Flags().GetInt("year")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		store, err := state.OpenReadOnly(statePath)
+		if err != nil {
+			fatal("Could not open state store", "error", err)
+		}
+		defer store.Close()
+
+		switch {
+		case moduleID != "":
+			histories, err := store.Module(account, moduleID)
+			if err != nil {
+				fatal("Could not read history", "error", err)
+			}
+			if len(histories) == 0 {
+				fatal("No history of module", "module", moduleID)
+			}
+			printModuleHistories(os.Stdout, histories)
+		case weekValue != "":
+			year, week, err := parseWeek(weekValue, year)
+			if err != nil {
+				fatal("Could not parse week", "error", err)
+			}
+			histories, err := store.Week(account, year, week)
+			if err != nil {
+				fatal("Could not read history", "error", err)
+			}
+			if len(histories) == 0 {
+				fatal("No history of week", "week", week, "year", year)
+			}
+			printModuleHistories(os.Stdout, histories)
+		default:
+			runs, err := store.Runs(account, limit)
+			if err != nil {
+				fatal("Could not read history", "error", err)
+			}
+			printRuns(os.Stdout, runs)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	statePath, _ := state.DefaultPath()
+	historyCmd.Flags().String("state", statePath, "The path to the state store")
+	historyCmd.Flags().String("account", "", "Only show the history of the account with the given name")
+	historyCmd.Flags().String("module", "", "Show the history of the module with the given ID")
+	historyCmd.Flags().String("week", "", "Show the history of the modules of the week, eg. 43 or 2026-W43")
+	historyCmd.Flags().Int("year", 0, "The year of --week (default is the current year)")
+	historyCmd.Flags().IntP("limit", "n", 20, "The maximum amount of runs to show (0 for all)")
+	historyCmd.MarkFlagsMutuallyExclusive("module", "week")
+}
+
+// Parses a week like "43" or "2026-W43". The year defaults to the current year
+func parseWeek(value string, year int) (int, int, error) {
+	weekValue := value
+	if y, w, ok := strings.Cut(strings.ToUpper(value), "-W"); ok {
+		parsed, err := strconv.Atoi(y)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid year in %q", value)
+		}
+		year, weekValue = parsed, w
+	}
+
+	week, err := strconv.Atoi(weekValue)
+	if err != nil || week < 1 || week > 53 {
+		return 0, 0, fmt.Errorf("invalid week %q", value)
+	}
+	if year == 0 {
+		year = time.Now().Year()
+	}
+	return year, week, nil
+}
+
+// Prints the runs as a table
+func printRuns(w io.Writer, runs []state.Run) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN\tSTARTED\tACCOUNT\tTIME\tMODULES\tVERSIONS\tUPDATED\tINSERTED\tDELETED\tFAILED\tERROR")
+	for _, run := range runs {
+		fmt.Fprintf(tw, "%v\t%s\t%s\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%s\n",
+			run.ID,
+			run.StartedAt.Local().Format(time.DateTime),
+			run.Account,
+			run.FinishedAt.Sub(run.StartedAt).Round(time.Second),
+			run.Modules,
+			run.Versions,
+			run.Updated,
+			run.Inserted,
+			run.Deleted,
+			run.Failed,
+			strings.ReplaceAll(run.Error, "\n", " "),
+		)
+	}
+	tw.Flush()
+}
+
+// Prints the versions of each module with what changed between them, and the changes made to its calendar event
+func printModuleHistories(w io.Writer, histories []state.ModuleHistory) {
+	for i, history := range histories {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		latest := history.Latest().Module
+		fmt.Fprintf(w, "%s, %s (%s", latest.Title, formatHistoryTime(latest.StartDate), history.ModuleID)
+		if history.Account != "" {
+			fmt.Fprintf(w, ", %s", history.Account)
+		}
+		fmt.Fprintln(w, ")")
+
+		type entry struct {
+			runID       uint64
+			time        time.Time
+			description string
+		}
+		var entries []entry
+		for j, version := range history.Versions {
+			description := "first scraped"
+			if j > 0 {
+				description = strings.Join(versionDiff(&history.Versions[j-1].Module, &version.Module), ", ")
+			}
+			entries = append(entries, entry{version.RunID, version.ScrapedAt, description})
+		}
+		for _, action := range history.Actions {
+			description := "calendar event " + actionVerbs[action.Type]
+			if action.Error != "" {
+				description = fmt.Sprintf("calendar event could not be %s: %s", actionVerbs[action.Type], strings.ReplaceAll(action.Error, "\n", " "))
+			}
+			entries = append(entries, entry{action.RunID, action.Time, description})
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].runID < entries[j].runID })
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, e := range entries {
+			fmt.Fprintf(tw, "  %s\trun %v\t%s\n", e.time.Local().Format(time.DateTime), e.runID, e.description)
+		}
+		tw.Flush()
+	}
+}
+
+// Returns the differences between two versions of a module, eg. "room 12 -> 14"
+func versionDiff(previous, current *lectigo.Module) []string {
+	var diffs []string
+	for _, field := range lectigo.DiffModuleFields(previous, current) {
+		diffs = append(diffs, fmt.Sprintf("%s %s -> %s", field.Field, diffValue(field.Old), diffValue(field.New)))
+	}
+	if len(diffs) == 0 {
+		diffs = append(diffs, "details changed")
+	}
+	return diffs
+}
+
+func formatHistoryTime(t time.Time) string {
+	return t.Format("Mon 2 Jan 15:04")
+}
@@ -4,9 +4,13 @@ Copyright © 2023 Mattis Møl Kristensen <mattismoel@gmail.com>
 package cmd
 
 import (
-	"log"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/mattismoel/lectigo/util"
+	"github.com/mattismoel/lectigo/pkg/export"
 	"github.com/spf13/cobra"
 )
 
@@ -16,9 +20,10 @@ var listSchoolsCmd = &cobra.Command{
 	Short: "Exports the schools registered at Lectio in given encoding scheme at given output path",
 	Long: `Exports the schools registered at Lectio in a given encoding scheme. Available schemes are:
 
-json, yaml, xml
+` + strings.Join(export.SchoolFormats(), ", ") + `
 
 The encoded file is exported at the given output path. The path should include at least the base filename. Extension is optional.
+Use - as the path to write to stdout.
 
 Example:
 
@@ -26,16 +31,43 @@ Example:
 	Run: func(cmd *cobra.Command, args []string) {
 		format, err := cmd.Flags().GetString("format")
 		if err != nil {
-			log.Fatalf("Could not get format flag: %v\n", err)
+			fatal("Could not get format flag", "error", err)
 		}
 		path, err := cmd.Flags().GetString("path")
 		if err != nil {
-			log.Fatalf("Could not get path flag: %v\n", err)
+			fatal("Could not get path flag", "error", err)
 		}
 
-		err = util.ExportSchools(format, path)
+		// Fail on an unknown format before fetching the schools
+		f, err := export.Lookup(format)
+		if err == nil && f.Schools == nil {
+			err = fmt.Errorf("format %q does not support schools", format)
+		}
+		if err != nil {
+			fatal("Unsupported format", "error", err, "formats", strings.Join(export.SchoolFormats(), ", "))
+		}
+
+		schools, err := loadSchools(cmd)
+		if err != nil {
+			fatal("Could not get schools list", "error", err)
+		}
+
+		if path != "-" {
+			// If file name does not have file extension, add it
+			if !strings.HasSuffix(path, "."+f.Extension) {
+				path += "." + f.Extension
+			}
+			err = os.MkdirAll(filepath.Dir(path), 0755)
+			if err != nil {
+				fatal("Could not create directory", "path", path, "error", err)
+			}
+		}
+
+		err = writeOutput(path, func(w io.Writer) error {
+			return export.WriteSchools(w, format, schools)
+		})
 		if err != nil {
-			log.Fatalf("Could not export schools list to %v format at path %q: %v\n", format, path, err)
+			fatal("Could not export schools list", "format", format, "path", path, "error", err)
 		}
 	},
 }
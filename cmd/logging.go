@@ -0,0 +1,139 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// The levels of --log-level
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// Parts of the keys of log attributes holding secrets, which are never logged
+var secretLogKeys = []string{"password", "passphrase", "secret", "token", "cookie"}
+
+// Sets the default logger to write to stderr at the level and in the format given by --log-level and --log-format
+func setupLogging(cmd *cobra.Command) error {
+	levelName, _ := cmd.Flags().GetString("log-level")
+	format, _ := cmd.Flags().GetString("log-format")
+
+	level, ok := logLevels[strings.ToLower(levelName)]
+	if !ok {
+		return fmt.Errorf("unknown log level %q (use debug, info, warn or error)", levelName)
+	}
+
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactSecrets}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("unknown log format %q (use text or json)", format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// Replaces the values of attributes holding secrets. Paths to files holding secrets are kept
+func redactSecrets(groups []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	if strings.HasSuffix(key, "path") || strings.HasSuffix(key, "file") {
+		return a
+	}
+	for _, secret := range secretLogKeys {
+		if strings.Contains(key, secret) {
+			return slog.String(a.Key, "[REDACTED]")
+		}
+	}
+	return a
+}
+
+// Returns the logger of the account, adding its name to every log if it has one
+func accountLogger(account accountConfig) *slog.Logger {
+	if account.Name == "" {
+		return slog.Default()
+	}
+	return slog.Default().With("account", account.Name)
+}
+
+// Logs the error and exits with exitError
+func fatal(msg string, args ...any) {
+	exitWith(exitError, msg, args...)
+}
+
+// Logs the error and exits with the exit code
+func exitWith(code int, msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(code)
+}
+
+// The warnings and errors logged through a warningHandler
+type warningLog struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+// Returns the logged warnings and starts over
+func (l *warningLog) take() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	warnings := l.warnings
+	l.warnings = nil
+	return warnings
+}
+
+// Records the warnings and errors passing through to the handler it wraps, even those below its level
+type warningHandler struct {
+	slog.Handler
+	log *warningLog
+}
+
+// Returns a logger recording the warnings and errors logged through it in the warning log
+func recordWarnings(logger *slog.Logger, log *warningLog) *slog.Logger {
+	return slog.New(&warningHandler{Handler: logger.Handler(), log: log})
+}
+
+func (h *warningHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn || h.Handler.Enabled(ctx, level)
+}
+
+func (h *warningHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn {
+		msg := r.Message
+		r.Attrs(func(a slog.Attr) bool {
+			a = redactSecrets(nil, a)
+			msg += fmt.Sprintf(" %s=%v", a.Key, a.Value)
+			return true
+		})
+		h.log.mu.Lock()
+		h.log.warnings = append(h.log.warnings, msg)
+		h.log.mu.Unlock()
+	}
+	if !h.Handler.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *warningHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &warningHandler{Handler: h.Handler.WithAttrs(attrs), log: h.log}
+}
+
+func (h *warningHandler) WithGroup(name string) slog.Handler {
+	return &warningHandler{Handler: h.Handler.WithGroup(name), log: h.log}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/pkg/notify"
+	"github.com/spf13/cobra"
+)
+
+// The notifications file, listing the channels changes are sent through
+type notificationsConfig struct {
+	QuietHours string          `yaml:"quietHours"` // Eg. "22:00-07:00". Applies to channels without their own
+	Changes    []string        `yaml:"changes"`    // The change types to send. Applies to channels without their own. Empty for all
+	Pending    string          `yaml:"pending"`    // The file queuing changes held back by quiet hours. Defaults to the user cache directory
+	Channels   []channelConfig `yaml:"channels"`
+}
+
+// A single notification channel of the notifications file
+type channelConfig struct {
+	Name        string            `yaml:"name"`        // Identifies the channel in errors. Defaults to its type
+	Type        string            `yaml:"type"`        // webhook, ntfy, discord, slack or smtp
+	URL         string            `yaml:"url"`         // The URL of webhook, discord and slack channels
+	Headers     map[string]string `yaml:"headers"`     // Extra headers of webhook channels
+	Server      string            `yaml:"server"`      // The ntfy server. Defaults to https://ntfy.sh
+	Topic       string            `yaml:"topic"`       // The ntfy topic
+	Token       string            `yaml:"token"`       // The ntfy access token
+	Priority    string            `yaml:"priority"`    // The ntfy priority
+	Host        string            `yaml:"host"`        // The SMTP server
+	Port        int               `yaml:"port"`        // The SMTP port. Defaults to 587
+	Username    string            `yaml:"username"`    // The SMTP username
+	Password    string            `yaml:"password"`    // The SMTP password
+	PasswordEnv string            `yaml:"passwordEnv"` // The environment variable holding the SMTP password
+	From        string            `yaml:"from"`        // The sender of emails
+	To          []string          `yaml:"to"`          // The recipients of emails
+	QuietHours  string            `yaml:"quietHours"`  // Overrides the quiet hours of the file
+	Changes     []string          `yaml:"changes"`     // Overrides the change types of the file
+}
+
+// notifyCmd represents the notify command
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Manages notifications about changed modules",
+}
+
+// notifyTestCmd represents the notify test command
+var notifyTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Sends an example change through every notification channel",
+	Long: `Sends an example change through every channel of the notifications file, ignoring quiet hours and change type filters.
+Point the channels at a local stand-in server (eg. a webhook receiver, an ntfy server or an SMTP catcher like MailHog) to try them out without a real service.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _ := cmd.Flags().GetString("notifications")
+		dispatcher, err := loadNotifications(path)
+		if err != nil {
+			fatal("Could not load notifications", "error", err)
+		}
+		for i := range dispatcher.Channels {
+			dispatcher.Channels[i].Filter = notify.Filter{}
+		}
+		// The queued changes are left for the next sync
+		dispatcher.PendingPath = ""
+
+		start := time.Now().Add(time.Hour).Truncate(time.Hour)
+		change := lectigo.Change{
+			Type: lectigo.ChangeCancelled,
+			Module: lectigo.Module{
+				Id:           "test",
+				Title:        "Test",
+				StartDate:    start,
+				EndDate:      start.Add(45 * time.Minute),
+				Location:     "1.23",
+				ModuleStatus: lectigo.StatusCancelled,
+			},
+		}
+
+		err = dispatcher.Notify(context.Background(), "test", []lectigo.Change{change})
+		if err != nil {
+			fatal("Could not send notifications", "error", err)
+		}
+		fmt.Printf("Sent an example change through %v channels\n", len(dispatcher.Channels))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(notifyCmd)
+	notifyCmd.AddCommand(notifyTestCmd)
+
+	notifyTestCmd.Flags().String("notifications", "notifications.yml", "The path to the notifications file")
+}
+
+// Reads the notifications file and creates its channels
+func loadNotifications(path string) (*notify.Dispatcher, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config notificationsConfig
+	err = yaml.Unmarshal(bytes, &config)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse notifications file %q: %w", path, err)
+	}
+	if len(config.Channels) == 0 {
+		return nil, fmt.Errorf("notifications file %q has no channels", path)
+	}
+
+	defaultFilter, err := newFilter(config.QuietHours, config.Changes)
+	if err != nil {
+		return nil, err
+	}
+
+	dispatcher := &notify.Dispatcher{PendingPath: config.Pending}
+	if dispatcher.PendingPath == "" {
+		dispatcher.PendingPath, err = notify.DefaultPendingPath()
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, channelConfig := range config.Channels {
+		name := channelConfig.Name
+		if name == "" {
+			name = fmt.Sprintf("%s channel %d", channelConfig.Type, i+1)
+		}
+
+		notifier, err := channelConfig.notifier()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		filter := defaultFilter
+		if channelConfig.QuietHours != "" || len(channelConfig.Changes) > 0 {
+			quietHours, changes := channelConfig.QuietHours, channelConfig.Changes
+			if quietHours == "" {
+				quietHours = config.QuietHours
+			}
+			if len(changes) == 0 {
+				changes = config.Changes
+			}
+			filter, err = newFilter(quietHours, changes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+
+		dispatcher.Channels = append(dispatcher.Channels, notify.Channel{Name: name, Notifier: notifier, Filter: filter})
+	}
+	return dispatcher, nil
+}
+
+// Creates the filter of the quiet hours and change types
+func newFilter(quietHours string, changes []string) (notify.Filter, error) {
+	var filter notify.Filter
+	if quietHours != "" {
+		var err error
+		filter.QuietHours, err = notify.ParseQuietHours(quietHours)
+		if err != nil {
+			return filter, err
+		}
+	}
+	for _, change := range changes {
+		changeType, err := lectigo.ParseChangeType(change)
+		if err != nil {
+			return filter, err
+		}
+		filter.Types = append(filter.Types, changeType)
+	}
+	return filter, nil
+}
+
+// Creates the notifier of the channel
+func (c *channelConfig) notifier() (notify.Notifier, error) {
+	switch c.Type {
+	case "webhook":
+		if c.URL == "" {
+			return nil, fmt.Errorf("webhook channels need a url")
+		}
+		return &notify.WebhookNotifier{URL: c.URL, Headers: c.Headers}, nil
+	case "ntfy":
+		if c.Topic == "" {
+			return nil, fmt.Errorf("ntfy channels need a topic")
+		}
+		return &notify.NtfyNotifier{Server: c.Server, Topic: c.Topic, Token: c.Token, Priority: c.Priority}, nil
+	case "discord", "slack":
+		if c.URL == "" {
+			return nil, fmt.Errorf("%s channels need a url", c.Type)
+		}
+		return &notify.ChatNotifier{Kind: notify.ChatKind(c.Type), URL: c.URL}, nil
+	case "smtp":
+		if c.Host == "" || c.From == "" || len(c.To) == 0 {
+			return nil, fmt.Errorf("smtp channels need a host, from and to")
+		}
+		password := c.Password
+		if c.PasswordEnv != "" {
+			password = os.Getenv(c.PasswordEnv)
+		}
+		return &notify.SMTPNotifier{
+			Host:     c.Host,
+			Port:     c.Port,
+			Username: c.Username,
+			Password: password,
+			From:     c.From,
+			To:       c.To,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown channel type %q (use webhook, ntfy, discord, slack or smtp)", c.Type)
+}
@@ -0,0 +1,234 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+// The exit codes of lego
+const (
+	exitOK                 = 0 // Synced, without changes to the calendar unless --detailedExitCode is given
+	exitError              = 1 // Any failure not listed below, eg. a broken config file
+	exitChanges            = 2 // Synced with changes to the calendar, with --detailedExitCode
+	exitLoginFailed        = 3 // Could not log in to Lectio
+	exitScrapeFailed       = 4 // Could not scrape the Lectio schedule, eg. as Lectio is unavailable
+	exitCalendarAuthFailed = 5 // Could not authenticate with Google Calendar
+	exitCalendarFailed     = 6 // Could not read or update Google Calendar
+	exitPartialFailure     = 7 // Some accounts or events failed while others were synced
+)
+
+// A phase of the sync of an account
+type syncPhase string
+
+const (
+	phaseLogin  syncPhase = "login"  // Starting the browser and logging in to Lectio
+	phaseScrape syncPhase = "scrape" // Scraping the schedule
+	phaseEvents syncPhase = "events" // Reading the events of the calendar
+	phaseUpdate syncPhase = "update" // Inserting, updating and deleting events
+	phaseNotify syncPhase = "notify" // Sending notifications of the changes
+)
+
+// The error of a sync, with the phase it failed in
+type phaseError struct {
+	phase syncPhase
+	err   error
+}
+
+func (e *phaseError) Error() string {
+	return e.err.Error()
+}
+
+func (e *phaseError) Unwrap() error {
+	return e.err
+}
+
+// The outcome of the sync of an account or of all accounts
+type syncStatus string
+
+const (
+	statusUnchanged syncStatus = "unchanged" // Synced without changes to the calendar
+	statusChanged   syncStatus = "changed"   // Synced with changes to the calendar
+	statusPartial   syncStatus = "partial"   // Some accounts or events failed while others were synced
+	statusFailed    syncStatus = "failed"    // Nothing was synced
+)
+
+// The report of a sync written by --report
+type syncReport struct {
+	StartedAt time.Time       `json:"startedAt"`
+	Duration  float64         `json:"duration"` // In seconds
+	Status    syncStatus      `json:"status"`
+	ExitCode  int             `json:"exitCode"`
+	Inserted  int             `json:"inserted"`
+	Updated   int             `json:"updated"`
+	Deleted   int             `json:"deleted"`
+	Failed    int             `json:"failed"` // The events that could not be inserted, updated or deleted
+	Accounts  []accountReport `json:"accounts"`
+}
+
+// The report of the sync of an account
+type accountReport struct {
+	Account    string                   `json:"account,omitempty"`
+	CalendarID string                   `json:"calendarId"`
+	Status     syncStatus               `json:"status"`
+	Inserted   int                      `json:"inserted"`
+	Updated    int                      `json:"updated"`
+	Deleted    int                      `json:"deleted"`
+	Failed     int                      `json:"failed"`
+	Phases     map[syncPhase]float64    `json:"phases"` // The seconds taken by each phase
+	Actions    []lectigo.CalendarAction `json:"actions"`
+	Failures   []lectigo.CalendarAction `json:"failures,omitempty"`
+	Changes    []lectigo.Change         `json:"changes,omitempty"`
+	Warnings   []string                 `json:"warnings,omitempty"`
+	Error      string                   `json:"error,omitempty"`
+	ErrorPhase syncPhase                `json:"errorPhase,omitempty"`
+}
+
+// Builds the report of the results of a sync started at startedAt
+func newSyncReport(startedAt time.Time, results []accountResult, detailedExitCode bool) syncReport {
+	report := syncReport{
+		StartedAt: startedAt,
+		Duration:  time.Since(startedAt).Seconds(),
+		Status:    resultsStatus(results),
+		ExitCode:  exitCode(results, detailedExitCode),
+		Accounts:  make([]accountReport, len(results)),
+	}
+	for i, r := range results {
+		a := newAccountReport(r)
+		report.Inserted += a.Inserted
+		report.Updated += a.Updated
+		report.Deleted += a.Deleted
+		report.Failed += a.Failed
+		report.Accounts[i] = a
+	}
+	return report
+}
+
+// Builds the report of the sync of an account
+func newAccountReport(r accountResult) accountReport {
+	report := accountReport{
+		Account:    r.account.Name,
+		CalendarID: r.account.CalendarID,
+		Status:     r.status(),
+		Phases:     make(map[syncPhase]float64),
+		Actions:    []lectigo.CalendarAction{},
+		Warnings:   r.warnings,
+	}
+	for phase, d := range r.phases {
+		report.Phases[phase] = d.Seconds()
+	}
+
+	if r.err != nil {
+		report.Error = r.err.Error()
+		var phaseErr *phaseError
+		if errors.As(r.err, &phaseErr) {
+			report.ErrorPhase = phaseErr.phase
+		}
+	}
+	if r.result != nil {
+		report.Inserted = r.result.Inserted
+		report.Updated = r.result.Updated
+		report.Deleted = r.result.Deleted
+		report.Failed = len(r.result.Failures)
+		report.Actions = append(report.Actions, r.result.Actions...)
+		report.Failures = r.result.Failures
+		report.Changes = r.result.Changes
+	}
+	return report
+}
+
+// Writes the report as indented JSON
+func writeReport(w io.Writer, report syncReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// Returns the outcome of the sync of the account
+func (r accountResult) status() syncStatus {
+	switch {
+	case r.err != nil:
+		return statusFailed
+	case len(r.result.Failures) > 0:
+		return statusPartial
+	case r.result.Inserted+r.result.Updated+r.result.Deleted > 0:
+		return statusChanged
+	}
+	return statusUnchanged
+}
+
+// Returns the outcome of the sync of all accounts
+func resultsStatus(results []accountResult) syncStatus {
+	failed, partial, changed := 0, false, false
+	for _, r := range results {
+		switch r.status() {
+		case statusFailed:
+			failed++
+		case statusPartial:
+			partial = true
+		case statusChanged:
+			changed = true
+		}
+	}
+
+	switch {
+	case failed > 0 && failed == len(results):
+		return statusFailed
+	case failed > 0 || partial:
+		return statusPartial
+	case changed:
+		return statusChanged
+	}
+	return statusUnchanged
+}
+
+// Returns the exit code of the results. When every account failed, the code tells why the first one failed. Syncs
+// changing the calendar only exit with exitChanges if detailed is true, so that they are not mistaken for failures
+func exitCode(results []accountResult, detailed bool) int {
+	switch resultsStatus(results) {
+	case statusFailed:
+		return errorExitCode(results[0].err)
+	case statusPartial:
+		return exitPartialFailure
+	case statusChanged:
+		if detailed {
+			return exitChanges
+		}
+	}
+	return exitOK
+}
+
+// Returns the exit code of the error of a failed sync
+func errorExitCode(err error) int {
+	var retrieveErr *oauth2.RetrieveError
+	var apiErr *googleapi.Error
+	switch {
+	case errors.Is(err, lectigo.ErrLoginFailed):
+		return exitLoginFailed
+	case errors.Is(err, lectigo.ErrLectioUnavailable):
+		return exitScrapeFailed
+	case errors.As(err, &retrieveErr), errors.As(err, &apiErr) && apiErr.Code == http.StatusUnauthorized:
+		return exitCalendarAuthFailed
+	}
+
+	var phaseErr *phaseError
+	if !errors.As(err, &phaseErr) {
+		return exitError
+	}
+	switch phaseErr.phase {
+	case phaseScrape:
+		return exitScrapeFailed
+	case phaseEvents, phaseUpdate:
+		return exitCalendarFailed
+	}
+	return exitError
+}
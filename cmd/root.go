@@ -20,6 +20,17 @@ var rootCmd = &cobra.Command{
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		err := applyConfig(cmd)
+		if err == nil {
+			err = setupLogging(cmd)
+		}
+		if err != nil {
+			// The usage does not help with a broken config file
+			cmd.SilenceUsage = true
+		}
+		return err
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -37,11 +48,10 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.lectigo.yaml)")
-
-	// Cobra also supports local flags, which will only run
-	// when this action is called directly.
-	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	rootCmd.PersistentFlags().String("config", "", "The config file (default is lectigo/config.yml in the user config directory)")
+	rootCmd.PersistentFlags().String("profile", "", "The profile of the config file to use (default is the defaultProfile of the config file)")
+	rootCmd.PersistentFlags().String("log-level", "info", "The lowest level of the logs written to stderr: debug, info, warn or error")
+	rootCmd.PersistentFlags().String("log-format", "text", "The format of the logs written to stderr: text or json")
 }
 
 
@@ -0,0 +1,114 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mattismoel/lectigo/pkg/export"
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+)
+
+// schoolsCmd represents the schools command
+var schoolsCmd = &cobra.Command{
+	Use:   "schools",
+	Short: "Looks up schools registered at Lectio",
+	Long:  `Looks up schools registered at Lectio. The list of schools is cached locally, and fetched again when it is older than 30 days or --refresh is given.`,
+}
+
+// schoolsSearchCmd represents the schools search command
+var schoolsSearchCmd = &cobra.Command{
+	Use:   "search <name>",
+	Short: "Searches the schools registered at Lectio by name",
+	Long: `Searches the schools registered at Lectio by name, ignoring case and diacritics and tolerating small typos. Available output formats are:
+
+table, ` + strings.Join(export.SchoolFormats(), ", ") + `
+
+Example:
+
+	lego schools search "århus katedral" -f json`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		schools, err := loadSchools(cmd)
+		if err != nil {
+			fatal("Could not get schools list", "error", err)
+		}
+
+		matches := util.SearchSchools(schools, strings.Join(args, " "))
+		if limit > 0 && len(matches) > limit {
+			matches = matches[:limit]
+		}
+
+		err = writeSchoolMatches(os.Stdout, format, matches)
+		if err != nil {
+			fatal("Could not write schools", "error", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schoolsCmd)
+	schoolsCmd.AddCommand(schoolsSearchCmd)
+
+	schoolsCmd.PersistentFlags().Bool("refresh", false, "Fetch the list of schools from Lectio instead of using the cached list")
+	schoolsCmd.PersistentFlags().String("cache", "", "The path to the cached list of schools (default is in the user cache directory)")
+	schoolsSearchCmd.Flags().StringP("format", "f", "table", "The output format (table, "+strings.Join(export.SchoolFormats(), ", ")+")")
+	schoolsSearchCmd.Flags().IntP("limit", "n", 10, "The maximum amount of schools to show (0 for all)")
+}
+
+// Loads the cached list of schools, using the --refresh and --cache flags if the command has them
+func loadSchools(cmd *cobra.Command) ([]util.School, error) {
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	cachePath, _ := cmd.Flags().GetString("cache")
+	userAgent, _ := cmd.Flags().GetString("userAgent")
+
+	if cachePath == "" {
+		var err error
+		cachePath, err = util.DefaultSchoolCachePath()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return util.LoadSchools(cachePath, refresh, userAgent)
+}
+
+// Returns the school ID of a school given by either its ID or its name
+func resolveSchoolID(cmd *cobra.Command, idOrName string) (string, error) {
+	if _, err := strconv.Atoi(idOrName); err == nil {
+		return idOrName, nil
+	}
+
+	schools, err := loadSchools(cmd)
+	if err != nil {
+		return "", fmt.Errorf("could not get schools list: %w", err)
+	}
+	return util.FindSchoolID(schools, idOrName)
+}
+
+// Writes the school matches in the given format
+func writeSchoolMatches(w io.Writer, format string, matches []util.SchoolMatch) error {
+	schools := make([]util.School, len(matches))
+	for i, match := range matches {
+		schools[i] = match.School
+	}
+
+	if format == "table" {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME")
+		for _, school := range schools {
+			fmt.Fprintf(tw, "%s\t%s\n", school.SchoolID, school.Name)
+		}
+		return tw.Flush()
+	}
+	return export.WriteSchools(w, format, schools)
+}
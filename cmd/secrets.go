@@ -0,0 +1,297 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+	"golang.org/x/term"
+)
+
+// The environment variable holding the Lectio password of the account given by flags
+const lectioPasswordEnv = "LECTIGO_PASSWORD"
+
+// The secret store opened by the current command
+var secretStore *util.SecretStore
+
+// secretsCmd represents the secrets command
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manages the encrypted store of Lectio passwords and Google tokens",
+	Long: `Manages the local secret store, which keeps Lectio passwords and Google OAuth tokens encrypted with a passphrase.
+The passphrase is read from the ` + util.SecretsPassphraseEnv + ` environment variable, or asked for when it is not set.
+
+When the secret store exists, sync looks up the Lectio password of the account in it, and keeps new Google OAuth tokens in it instead of in a token file.`,
+}
+
+// secretsSetPasswordCmd represents the secrets set-password command
+var secretsSetPasswordCmd = &cobra.Command{
+	Use:   "set-password",
+	Short: "Saves a Lectio password to the secret store",
+	Long: `Saves the Lectio password of a user to the secret store. The password is asked for without being shown, or read from standard input when it is not a terminal.
+
+Example:
+
+	lego secrets set-password -u username1234 -s 133`,
+	Run: func(cmd *cobra.Command, args []string) {
+		username, _ := cmd.Flags().GetString("username")
+		schoolID, _ := cmd.Flags().GetString("schoolID")
+
+		schoolID, err := resolveSchoolID(cmd, schoolID)
+		if err != nil {
+			fatal("Could not find school", "error", err)
+		}
+
+		password, err := readSecret(fmt.Sprintf("Lectio password for %s: ", username))
+		if err != nil {
+			fatal("Could not read password", "error", err)
+		}
+		if password == "" {
+			fatal("The password must not be empty")
+		}
+
+		store, err := openSecretStore(cmd, true)
+		if err != nil {
+			fatal("Could not open secret store", "error", err)
+		}
+		store.Set(passwordSecretName(schoolID, username), password)
+		err = store.Save()
+		if err != nil {
+			fatal("Could not save secret store", "error", err)
+		}
+		fmt.Printf("Saved password as %q\n", passwordSecretName(schoolID, username))
+	},
+}
+
+// secretsImportTokenCmd represents the secrets import-token command
+var secretsImportTokenCmd = &cobra.Command{
+	Use:   "import-token <token file>",
+	Short: "Moves a Google OAuth token file into the secret store",
+	Long:  `Moves a Google OAuth token file into the secret store and deletes the file. The token is used when the same --tokenPath is given to sync.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := args[0]
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			fatal("Could not read token file", "error", err)
+		}
+		token := &oauth2.Token{}
+		err = json.Unmarshal(bytes, token)
+		if err != nil {
+			fatal("Could not parse token file", "error", err)
+		}
+
+		store, err := openSecretStore(cmd, true)
+		if err != nil {
+			fatal("Could not open secret store", "error", err)
+		}
+		tokenStore := &util.SecretTokenStore{Store: store, Name: tokenSecretName(path)}
+		err = tokenStore.SaveToken(token)
+		if err != nil {
+			fatal("Could not save secret store", "error", err)
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			fatal("Could not delete token file", "error", err)
+		}
+	},
+}
+
+// secretsListCmd represents the secrets list command
+var secretsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the names of the secrets in the secret store",
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := openSecretStore(cmd, false)
+		if err != nil {
+			fatal("Could not open secret store", "error", err)
+		}
+		if store == nil {
+			fatal("No secret store exists")
+		}
+		for _, name := range store.Names() {
+			fmt.Println(name)
+		}
+	},
+}
+
+// secretsDeleteCmd represents the secrets delete command
+var secretsDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Deletes a secret from the secret store",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := openSecretStore(cmd, false)
+		if err != nil {
+			fatal("Could not open secret store", "error", err)
+		}
+		if store == nil || !store.Delete(args[0]) {
+			fatal("No such secret", "name", args[0])
+		}
+		err = store.Save()
+		if err != nil {
+			fatal("Could not save secret store", "error", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(secretsCmd)
+	secretsCmd.AddCommand(secretsSetPasswordCmd, secretsImportTokenCmd, secretsListCmd, secretsDeleteCmd)
+
+	rootCmd.PersistentFlags().String("secrets", "", "The path to the encrypted secret store (default is lectigo/secrets.enc in the user config directory)")
+
+	secretsSetPasswordCmd.Flags().StringP("username", "u", "", "Lectio username")
+	secretsSetPasswordCmd.Flags().StringP("schoolID", "s", "", "Lectio school ID or school name")
+	secretsSetPasswordCmd.MarkFlagRequired("username")
+	secretsSetPasswordCmd.MarkFlagRequired("schoolID")
+}
+
+// Opens the secret store given by --secrets, asking for the passphrase if it is not in the environment. If create is
+// false and no store exists, nil is returned
+func openSecretStore(cmd *cobra.Command, create bool) (*util.SecretStore, error) {
+	if secretStore != nil {
+		return secretStore, nil
+	}
+
+	path, _ := cmd.Flags().GetString("secrets")
+	if path == "" {
+		var err error
+		path, err = util.DefaultSecretStorePath()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	exists := util.SecretStoreExists(path)
+	if !exists && !create {
+		return nil, nil
+	}
+
+	passphrase, ok := os.LookupEnv(util.SecretsPassphraseEnv)
+	if !ok {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return nil, fmt.Errorf("the passphrase of the secret store must be given in %s", util.SecretsPassphraseEnv)
+		}
+		var err error
+		passphrase, err = promptSecret("Passphrase of secret store: ")
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			confirmed, err := promptSecret("Repeat passphrase: ")
+			if err != nil {
+				return nil, err
+			}
+			if confirmed != passphrase {
+				return nil, errors.New("the passphrases do not match")
+			}
+		}
+	}
+	if passphrase == "" {
+		return nil, errors.New("the passphrase of the secret store must not be empty")
+	}
+
+	store, err := util.OpenSecretStore(path, []byte(passphrase))
+	if err != nil {
+		return nil, err
+	}
+	secretStore = store
+	return store, nil
+}
+
+// Fills in the Lectio password of the account, unless it logs in with cookies. The password is taken from the first of
+// the password file, the environment variable of the account, the secret store and a prompt on the terminal
+func resolvePassword(cmd *cobra.Command, account *accountConfig) error {
+	if account.Cookies != "" || account.Password != "" {
+		return nil
+	}
+	if account.Username == "" {
+		return errors.New("a username is needed to log in with a password")
+	}
+
+	if account.PasswordFile != "" {
+		bytes, err := os.ReadFile(account.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("could not read password file: %w", err)
+		}
+		account.Password = strings.TrimRight(string(bytes), "\r\n")
+		if account.Password == "" {
+			return fmt.Errorf("password file %q is empty", account.PasswordFile)
+		}
+		return nil
+	}
+
+	if account.PasswordEnv != "" {
+		if password := os.Getenv(account.PasswordEnv); password != "" {
+			account.Password = password
+			return nil
+		}
+	}
+
+	store, err := openSecretStore(cmd, false)
+	if err != nil {
+		return fmt.Errorf("could not open secret store: %w", err)
+	}
+	if store != nil {
+		if password, ok := store.Get(passwordSecretName(account.SchoolID, account.Username)); ok {
+			account.Password = password
+			return nil
+		}
+	}
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		account.Password, err = promptSecret(fmt.Sprintf("Lectio password for %s: ", account.Username))
+		if err != nil {
+			return err
+		}
+		if account.Password != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("no password found for %q (use a password file, %s, the secret store or cookies)", account.Username, lectioPasswordEnv)
+}
+
+// Asks for a secret on the terminal without showing it
+func promptSecret(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	bytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("could not read from terminal: %w", err)
+	}
+	return string(bytes), nil
+}
+
+// Asks for a secret on the terminal, or reads the first line of standard input when it is not a terminal
+func readSecret(prompt string) (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return promptSecret(prompt)
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Returns the name of the Lectio password of the user in the secret store
+func passwordSecretName(schoolID, username string) string {
+	return fmt.Sprintf("lectio:%s:%s", schoolID, username)
+}
+
+// Returns the name of the Google OAuth token of the token file in the secret store
+func tokenSecretName(tokenPath string) string {
+	return "google-token:" + strings.TrimSuffix(filepath.Base(tokenPath), ".json")
+}
@@ -0,0 +1,548 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Shows a week of the Lectio schedule in the terminal",
+	Long: `Shows a week of the Lectio schedule as a grid in the terminal, with a column for every day. Cancelled modules are
+struck through in red and changed modules are highlighted in yellow.
+
+Every scraped week is kept in a local cache, which is shown when Lectio cannot be reached or --offline is given.
+
+Examples:
+
+	lego show -u username -s 123
+	lego show -u username -s 123 --week +1
+	lego show -u username -s 123 --week 2026-W43 --offline
+	lego show today -u username -s 123`,
+	Run: func(cmd *cobra.Command, args []string) {
+		weekValue, _ := cmd.Flags().GetString("week")
+		year, week, err := parseShowWeek(weekValue, time.Now())
+		if err != nil {
+			fatal("Could not parse week", "error", err)
+		}
+
+		schedule := loadShowSchedule(cmd, year, week)
+		view := newWeekView(cmd)
+		view.printWeek(os.Stdout, schedule, year, week)
+	},
+}
+
+// showTodayCmd represents the show today command
+var showTodayCmd = &cobra.Command{
+	Use:   "today",
+	Short: "Shows the modules of today as a compact list",
+	Run: func(cmd *cobra.Command, args []string) {
+		now := time.Now()
+		year, week := now.ISOWeek()
+
+		schedule := loadShowSchedule(cmd, year, week)
+		view := newWeekView(cmd)
+		view.printDay(os.Stdout, schedule, now)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+	showCmd.AddCommand(showTodayCmd)
+
+	for _, cmd := range []*cobra.Command{showCmd, showTodayCmd} {
+		addLectioFlags(cmd)
+		cmd.Flags().Bool("offline", false, "Show the cached schedule without logging in to Lectio")
+		cmd.Flags().String("snapshot", "", "Show the modules of a snapshot instead of scraping Lectio")
+		cmd.Flags().String("scheduleCache", "", "The path to the cache of scraped weeks (default is in the user cache directory)")
+		cmd.Flags().String("color", "auto", "When to colour the output (auto, always or never)")
+		cmd.Flags().Int("width", 0, "The width of the output (default is the width of the terminal)")
+	}
+	showCmd.Flags().String("week", "", "The week to show, eg. 43, 2026-W43 or +1 for next week (default is the current week)")
+}
+
+// The modules to show and where they came from
+type showSchedule struct {
+	modules  map[string]lectigo.Module
+	cachedAt time.Time // The time the modules were scraped, if they were read from the cache or a snapshot
+}
+
+// Parses the week of the --week flag relative to now. Weeks like +1 and -2 are relative to the current week
+func parseShowWeek(value string, now time.Time) (int, int, error) {
+	if value == "" {
+		year, week := now.ISOWeek()
+		return year, week, nil
+	}
+	if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+		offset, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid week offset %q", value)
+		}
+		year, week := now.AddDate(0, 0, 7*offset).ISOWeek()
+		return year, week, nil
+	}
+	return parseWeek(value, now.Year())
+}
+
+// Returns the modules of the week, scraped from Lectio, or read from the cache when offline or when Lectio fails
+func loadShowSchedule(cmd *cobra.Command, year, week int) showSchedule {
+	snapshotPath, _ := cmd.Flags().GetString("snapshot")
+	offline, _ := cmd.Flags().GetBool("offline")
+	cachePath, _ := cmd.Flags().GetString("scheduleCache")
+
+	if snapshotPath != "" {
+		snapshot, err := lectigo.LoadSnapshot(snapshotPath)
+		if err != nil {
+			fatal("Could not load snapshot", "error", err)
+		}
+		return showSchedule{modules: snapshot.Modules, cachedAt: snapshot.ScrapedAt}
+	}
+
+	account := schoolAccountFromFlags(cmd)
+	if cachePath == "" {
+		var err error
+		cachePath, err = defaultScheduleCachePath(account)
+		if err != nil {
+			fatal("Could not get schedule cache path", "error", err)
+		}
+	}
+
+	cache, err := loadScheduleCache(cachePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		cache = &scheduleCache{Snapshot: lectigo.Snapshot{SchoolID: account.SchoolID, Username: account.Username, Target: account.target()}}
+	} else if err != nil {
+		fatal("Could not load schedule cache", "error", err)
+	}
+
+	if !offline {
+		modules, err := scrapeWeek(cmd, account, year, week)
+		if err == nil {
+			updateScheduleCache(cache, modules, year, week)
+			err = saveScheduleCache(cache, cachePath)
+			if err != nil {
+				slog.Warn("Could not save schedule cache", "error", err)
+			}
+			return showSchedule{modules: modules}
+		}
+		slog.Warn("Could not get the schedule from Lectio, showing the cached schedule", "error", err)
+	}
+
+	if cache.Modules == nil {
+		fatal("No schedule has been cached yet", "path", cachePath)
+	}
+	scrapedAt, ok := cache.WeeksScrapedAt[isoWeekKey(year, week)]
+	if !ok {
+		fatal("The week has not been cached", "week", week, "year", year, "path", cachePath)
+	}
+	return showSchedule{modules: cache.Modules, cachedAt: scrapedAt}
+}
+
+// Scrapes the modules of a single week of the account
+func scrapeWeek(cmd *cobra.Command, account accountConfig, year, week int) (map[string]lectigo.Module, error) {
+	err := resolvePassword(cmd, &account)
+	if err != nil {
+		return nil, fmt.Errorf("could not get Lectio password: %w", err)
+	}
+
+	l, err := newLectio(account, syncOptionsFromFlags(cmd), accountLogger(account))
+	if err != nil {
+		return nil, err
+	}
+	defer l.Cancel()
+
+	modules, err := l.GetScheduleOfYear(year, week, account.target())
+	if err != nil {
+		return nil, fmt.Errorf("could not get Lectio schedule: %w", err)
+	}
+	return modules, nil
+}
+
+// Returns the default path of the schedule cache of the account
+func defaultScheduleCachePath(account accountConfig) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	name := account.SchoolID
+	if account.Username != "" {
+		name += "-" + account.Username
+	}
+	if target := account.target(); target.Type != lectigo.ScheduleSelf {
+		name += "-" + string(target.Type) + "-" + target.ID
+	}
+	return filepath.Join(dir, "lectigo", "schedule-"+name+".json"), nil
+}
+
+// The weeks scraped by show, kept to be shown offline
+type scheduleCache struct {
+	lectigo.Snapshot
+	WeeksScrapedAt map[string]time.Time `json:"weeksScrapedAt"` // The time each week was last scraped, eg. by "2026-W43"
+}
+
+// Reads the schedule cache at path
+func loadScheduleCache(path string) (*scheduleCache, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cache := &scheduleCache{}
+	err = json.Unmarshal(b, cache)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse schedule cache %q: %w", path, err)
+	}
+	return cache, nil
+}
+
+// Replaces the cached modules of the week with the scraped modules
+func updateScheduleCache(cache *scheduleCache, modules map[string]lectigo.Module, year, week int) {
+	if cache.Modules == nil {
+		cache.Modules = make(map[string]lectigo.Module)
+	}
+	if cache.WeeksScrapedAt == nil {
+		cache.WeeksScrapedAt = make(map[string]time.Time)
+	}
+	for id, m := range cache.Modules {
+		if y, w := m.StartDate.ISOWeek(); y == year && w == week {
+			delete(cache.Modules, id)
+		}
+	}
+	for id, m := range modules {
+		cache.Modules[id] = m
+	}
+	cache.ScrapedAt = time.Now()
+	cache.WeeksScrapedAt[isoWeekKey(year, week)] = cache.ScrapedAt
+}
+
+func saveScheduleCache(cache *scheduleCache, path string) error {
+	b, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// Returns the ISO week as text, eg. "2026-W43"
+func isoWeekKey(year, week int) string {
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// ANSI escape sequences of the styles of the view
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiDim       = "\x1b[2m"
+	ansiCancelled = "\x1b[9;31m" // Struck through and red
+	ansiChanged   = "\x1b[1;33m" // Bold and yellow
+)
+
+// Renders modules in the terminal
+type weekView struct {
+	color bool
+	width int
+}
+
+// Creates a view from the --color and --width flags
+func newWeekView(cmd *cobra.Command) *weekView {
+	colorMode, _ := cmd.Flags().GetString("color")
+	width, _ := cmd.Flags().GetInt("width")
+
+	view := &weekView{width: width}
+	isTerminal := term.IsTerminal(int(os.Stdout.Fd()))
+	switch colorMode {
+	case "always":
+		view.color = true
+	case "never":
+		view.color = false
+	case "auto":
+		view.color = isTerminal && os.Getenv("NO_COLOR") == ""
+	default:
+		fatal("Unknown color mode (use auto, always or never)", "color", colorMode)
+	}
+
+	if view.width <= 0 {
+		view.width = 120
+		if isTerminal {
+			if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+				view.width = w
+			}
+		}
+	}
+	return view
+}
+
+// Returns s in the style if the view is coloured
+func (v *weekView) style(s, style string) string {
+	if !v.color || style == "" || s == "" {
+		return s
+	}
+	return style + s + ansiReset
+}
+
+// Returns the style of the title of a module
+func statusStyle(m *lectigo.Module) string {
+	switch m.ModuleStatus {
+	case lectigo.StatusCancelled:
+		return ansiCancelled
+	case lectigo.StatusChanged:
+		return ansiChanged
+	}
+	return ""
+}
+
+// A line of a cell with the style it is shown in
+type cellLine struct {
+	text  string
+	style string
+}
+
+// Prints the week as a grid with a column for every day and a row for every start time
+func (v *weekView) printWeek(w io.Writer, schedule showSchedule, year, week int) {
+	location := scheduleLocation()
+	monday := util.ISOWeekStart(year, week, location)
+	sunday := monday.AddDate(0, 0, 7)
+
+	modules := weekModules(schedule.modules, monday, sunday)
+	fmt.Fprintf(w, "Week %v, %v (%s - %s)\n", week, year, monday.Format("2 Jan"), sunday.AddDate(0, 0, -1).Format("2 Jan"))
+	if len(modules) == 0 {
+		fmt.Fprintln(w, "No modules this week")
+		v.printCachedAt(w, schedule)
+		return
+	}
+
+	// Weekends are only shown when they have modules
+	dayCount := 5
+	for _, m := range modules {
+		if m.EndDate.After(monday.AddDate(0, 0, 5)) {
+			dayCount = 7
+		}
+	}
+	days := make([]time.Time, dayCount)
+	for i := range days {
+		days[i] = monday.AddDate(0, 0, i)
+	}
+
+	// The rows of the grid are the all-day modules followed by every start time of the week
+	var startTimes []string
+	for _, m := range modules {
+		if !m.AllDay && !slices.Contains(startTimes, m.StartDate.Format("15:04")) {
+			startTimes = append(startTimes, m.StartDate.Format("15:04"))
+		}
+	}
+	slices.Sort(startTimes)
+
+	var rows [][][]cellLine
+	allDayRow := make([][]cellLine, dayCount)
+	hasAllDay := false
+	for i, day := range days {
+		for _, m := range modules {
+			if m.AllDay && m.StartDate.Before(day.AddDate(0, 0, 1)) && m.EndDate.After(day) {
+				allDayRow[i] = v.appendBlock(allDayRow[i], &m)
+				hasAllDay = true
+			}
+		}
+	}
+	if hasAllDay {
+		rows = append(rows, allDayRow)
+	}
+	for _, startTime := range startTimes {
+		row := make([][]cellLine, dayCount)
+		for i, day := range days {
+			for _, m := range modules {
+				if !m.AllDay && daysBetween(day, m.StartDate) == 0 && m.StartDate.Format("15:04") == startTime {
+					row[i] = v.appendBlock(row[i], &m)
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	// Every column has a border on its left, and the last column on its right as well
+	columnWidth := max((v.width-1)/dayCount-1, 12)
+	border := func(left, middle, right string) string {
+		segment := strings.Repeat("─", columnWidth)
+		return left + strings.Repeat(segment+middle, dayCount-1) + segment + right
+	}
+
+	today := util.RoundDateToDay(time.Now().In(location))
+	fmt.Fprintln(w, border("┌", "┬", "┐"))
+	for _, day := range days {
+		style := ""
+		if day.Equal(today) {
+			style = ansiBold
+		}
+		fmt.Fprint(w, "│"+v.cell(cellLine{day.Format("Mon 2 Jan"), style}, columnWidth))
+	}
+	fmt.Fprintln(w, "│")
+
+	for _, row := range rows {
+		fmt.Fprintln(w, border("├", "┼", "┤"))
+		height := 0
+		for _, lines := range row {
+			height = max(height, len(lines))
+		}
+		for i := 0; i < height; i++ {
+			for _, lines := range row {
+				line := cellLine{}
+				if i < len(lines) {
+					line = lines[i]
+				}
+				fmt.Fprint(w, "│"+v.cell(line, columnWidth))
+			}
+			fmt.Fprintln(w, "│")
+		}
+	}
+	fmt.Fprintln(w, border("└", "┴", "┘"))
+	v.printCachedAt(w, schedule)
+}
+
+// Appends the block of a module to the lines of a cell, separated from the modules before it by an empty line
+func (v *weekView) appendBlock(lines []cellLine, m *lectigo.Module) []cellLine {
+	if len(lines) > 0 {
+		lines = append(lines, cellLine{})
+	}
+	timeText := "All day"
+	if !m.AllDay {
+		timeText = m.StartDate.Format("15:04") + "-" + m.EndDate.Format("15:04")
+	}
+	lines = append(lines, cellLine{timeText, ansiDim}, cellLine{m.Title, statusStyle(m)})
+	if rooms := m.RoomNames(); rooms != "" {
+		lines = append(lines, cellLine{rooms, ""})
+	}
+	if teachers := m.TeacherInitials(); teachers != "" {
+		lines = append(lines, cellLine{teachers, ""})
+	}
+	// Without colours the status is written out
+	if !v.color && m.ModuleStatus != lectigo.StatusNormal {
+		lines = append(lines, cellLine{"[" + string(m.ModuleStatus) + "]", ""})
+	}
+	return lines
+}
+
+// Returns the line padded or shortened to the width of a column, with a space on each side
+func (v *weekView) cell(line cellLine, width int) string {
+	text := truncate(line.text, width-2)
+	padding := strings.Repeat(" ", width-2-len([]rune(text)))
+	return " " + v.style(text, line.style) + padding + " "
+}
+
+// Prints the modules of the day of now as a list, marking the module taking place now
+func (v *weekView) printDay(w io.Writer, schedule showSchedule, now time.Time) {
+	day := util.RoundDateToDay(now)
+	modules := weekModules(schedule.modules, day, day.AddDate(0, 0, 1))
+
+	fmt.Fprintln(w, v.style(now.Format("Monday 2 January 2006"), ansiBold))
+	if len(modules) == 0 {
+		fmt.Fprintln(w, "No modules today")
+		v.printCachedAt(w, schedule)
+		return
+	}
+
+	type row struct {
+		module                       *lectigo.Module
+		time, title, rooms, teachers string
+	}
+	rows := make([]row, len(modules))
+	widths := make([]int, 3)
+	for i := range modules {
+		m := &modules[i]
+		r := row{module: m, time: "All day", title: m.Title, rooms: m.RoomNames(), teachers: m.TeacherInitials()}
+		if !m.AllDay {
+			r.time = m.StartDate.Format("15:04") + "-" + m.EndDate.Format("15:04")
+		}
+		rows[i] = r
+		widths[0] = max(widths[0], len([]rune(r.time)))
+		widths[1] = max(widths[1], len([]rune(r.title)))
+		widths[2] = max(widths[2], len([]rune(r.rooms)))
+	}
+
+	for _, r := range rows {
+		marker := "  "
+		if !r.module.AllDay && !now.Before(r.module.StartDate) && now.Before(r.module.EndDate) {
+			marker = "▶ "
+		}
+		var line strings.Builder
+		line.WriteString(marker)
+		line.WriteString(v.style(pad(r.time, widths[0]), ansiDim) + "  ")
+		line.WriteString(v.style(r.title, statusStyle(r.module)) + pad("", widths[1]-len([]rune(r.title))) + "  ")
+		line.WriteString(pad(r.rooms, widths[2]) + "  ")
+		line.WriteString(r.teachers)
+		if r.module.ModuleStatus != lectigo.StatusNormal {
+			line.WriteString("  " + v.style(string(r.module.ModuleStatus), statusStyle(r.module)))
+		}
+		fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	}
+	v.printCachedAt(w, schedule)
+}
+
+// Tells when the modules were scraped if they were not scraped now
+func (v *weekView) printCachedAt(w io.Writer, schedule showSchedule) {
+	if !schedule.cachedAt.IsZero() {
+		fmt.Fprintln(w, v.style("Scraped "+schedule.cachedAt.Local().Format("2006-01-02 15:04"), ansiDim))
+	}
+}
+
+// Returns the time zone of Lectio schedules, or the local time zone if its time zone data is missing
+func scheduleLocation() *time.Location {
+	location, err := time.LoadLocation("Europe/Copenhagen")
+	if err != nil {
+		return time.Local
+	}
+	return location
+}
+
+// Returns the modules taking place between from and to, sorted by start time
+func weekModules(modules map[string]lectigo.Module, from, to time.Time) []lectigo.Module {
+	var inRange []lectigo.Module
+	for _, m := range lectigo.SortedModules(modules) {
+		if m.StartDate.Before(to) && m.EndDate.After(from) {
+			inRange = append(inRange, m)
+		}
+	}
+	return inRange
+}
+
+// Returns the amount of calendar days from the day of from to the day of to
+func daysBetween(from, to time.Time) int {
+	from, to = util.RoundDateToDay(from), util.RoundDateToDay(to.In(from.Location()))
+	return int(to.Sub(from).Round(24*time.Hour) / (24 * time.Hour))
+}
+
+// Shortens s to at most width characters, ending it with an ellipsis if it was shortened
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	if width <= 1 {
+		return string(runes[:width])
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// Pads s with spaces to width characters
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-len([]rune(s)), 0))
+}
@@ -0,0 +1,67 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/spf13/cobra"
+)
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Saves the current Lectio schedule to a file",
+	Long: `Scrapes the Lectio schedule and saves it to a JSON file together with the time of the scrape, the school and the user.
+Two snapshots can be compared with the diff command.
+
+Examples:
+
+	lego snapshot -u username -s 123 -w 4
+	lego snapshot -u username -s 123 -o before.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		output, _ := cmd.Flags().GetString("output")
+		opts := syncOptionsFromFlags(cmd)
+		account := lectioAccountFromFlags(cmd)
+
+		if output == "" {
+			output = fmt.Sprintf("snapshot-%s.json", time.Now().Format("2006-01-02-150405"))
+		}
+
+		l, err := newLectio(account, opts, accountLogger(account))
+		if err != nil {
+			fatal("Could not start Lectio", "error", err)
+		}
+
+		scrapedAt := time.Now()
+		modules, err := scrapeModules(l, account, opts)
+		l.Cancel()
+		if err != nil {
+			fatal("Could not scrape Lectio", "error", err)
+		}
+
+		snapshot := &lectigo.Snapshot{
+			ScrapedAt: scrapedAt,
+			SchoolID:  account.SchoolID,
+			Username:  account.Username,
+			Target:    account.target(),
+			Weeks:     opts.weeks,
+			Modules:   modules,
+		}
+		err = snapshot.Save(output)
+		if err != nil {
+			fatal("Could not save snapshot", "error", err)
+		}
+		fmt.Printf("Saved %v modules to %s\n", len(modules), output)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+	addLectioFlags(snapshotCmd)
+
+	snapshotCmd.Flags().StringP("output", "o", "", "The path to save the snapshot to (default is snapshot-<time>.json)")
+}
@@ -4,12 +4,19 @@ Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/pkg/notify"
+	"github.com/mattismoel/lectigo/pkg/state"
 	"github.com/mattismoel/lectigo/util"
 	"github.com/spf13/cobra"
 	"golang.org/x/oauth2/google"
@@ -28,83 +35,571 @@ import (
 var syncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Syncs a Lectio schedule with a Google Calendar",
-	Long:  `Synchronises a users Lectio scedule with Google Calendar. The users Lectio login info as well as Google Calendar info is provided.`,
+	Long: `Synchronises a users Lectio scedule with Google Calendar. The users Lectio login info as well as Google Calendar info is provided.
+
+--report writes a JSON report of the sync, with the counts, the events inserted, updated and deleted, the warnings,
+the errors and the time taken by each phase of every account. The exit code tells how the sync went:
+
+	0  synced
+	1  failed for another reason, eg. a broken config file
+	2  synced with changes to the calendar (only with --detailedExitCode)
+	3  could not log in to Lectio
+	4  could not scrape the Lectio schedule, eg. as Lectio is unavailable
+	5  could not authenticate with Google Calendar
+	6  could not read or update Google Calendar
+	7  some accounts or events failed while others were synced`,
 	Run: func(cmd *cobra.Command, args []string) {
-		username, _ := cmd.Flags().GetString("username")
-		password, _ := cmd.Flags().GetString("password")
-		schoolID, _ := cmd.Flags().GetString("schoolID")
-		calendarID, _ := cmd.Flags().GetString("calendarID")
-		tokenPath, _ := cmd.Flags().GetString("tokenPath")
-		weeks, _ := cmd.Flags().GetInt("weeks")
-		hideCancelled, _ := cmd.Flags().GetBool("hideCancelled")
-		decodeClass, _ := cmd.Flags().GetBool("decodeClass")
-
-		fmt.Println("Attempting to sync Lectio and Google Calendar...")
-
-		// Reads the credentials file and creates a config from it - this is used to create the client
-		bytes, err := os.ReadFile("credentials.json")
-		if err != nil {
-			log.Fatalf("Could not read contents of credentials.json: %v\n", err)
+		accountsPath, _ := cmd.Flags().GetString("accounts")
+		opts := syncOptionsFromFlags(cmd)
+
+		if accountsPath != "" {
+			syncAccounts(cmd, accountsPath, opts)
+			return
+		}
+
+		account, client := prepareFlagAccount(cmd, opts)
+		startedAt := time.Now()
+
+		slog.Info("Syncing Lectio and Google Calendar")
+
+		s := newAccountSyncer(account, client, opts)
+		r := s.run()
+		s.close() // End browser instance
+		if r.err != nil {
+			slog.Error("Could not sync", "error", r.err)
+		} else if !reportToStdout(cmd) {
+			fmt.Println(r.result)
 		}
+		finishSync(cmd, startedAt, []accountResult{r})
+	},
+}
+
+// Options shared by all accounts of a sync
+type syncOptions struct {
+	weeks            int
+	hideCancelled    bool
+	decodeClass      bool
+	abbreviations    string
+	learnTeams       bool   // Learn the titles of teams the abbreviations can not decode from their pages in Lectio
+	teamCachePath    string // The path to the cache of learned team titles
+	blacklistPath    string
+	blacklist        *[]lectigo.ClassesToIgnore // Classes to ignore given by the profile. Read from blacklistPath if nil
+	filtersPath      string                     // The path to the filter rules. Empty for none
+	filter           *lectigo.Filter            // Filter rules given by the profile. Read from filtersPath if nil
+	transform        *lectigo.Transform         // Changes the modules before they are synced. Nil for none
+	credentialsPath  string
+	details          bool
+	detailsCachePath string
+	exams            bool
+	examReminders    []int
+	scrapeOptions    lectigo.ScrapeOptions
+	notifier         *notify.Dispatcher // Sends the changes of each account. Nil for no notifications
+	statePath        string             // The path to the state store. Empty to not record syncs
+}
 
-		config, err := google.ConfigFromJSON(bytes, "https://www.googleapis.com/auth/calendar.calendarlist.readonly", "https://www.googleapis.com/auth/calendar.events")
+// Serialises access to the state store
+var stateMu sync.Mutex
+
+// Writes the report of the results if --report is given, and exits with the exit code of the results
+func finishSync(cmd *cobra.Command, startedAt time.Time, results []accountResult) {
+	reportPath, _ := cmd.Flags().GetString("report")
+	detailed, _ := cmd.Flags().GetBool("detailedExitCode")
+	report := newSyncReport(startedAt, results, detailed)
+
+	if reportPath != "" {
+		err := writeOutput(reportPath, func(w io.Writer) error {
+			return writeReport(w, report)
+		})
 		if err != nil {
-			log.Fatalf("Could not create config from credentials.json")
+			fatal("Could not write report", "error", err)
 		}
+	}
+	if report.Status == statusPartial {
+		slog.Error("Some accounts or events failed to sync")
+	}
+	if report.ExitCode != exitOK {
+		os.Exit(report.ExitCode)
+	}
+}
+
+// Reports whether the report is written to stdout, in which case the summary is not printed
+func reportToStdout(cmd *cobra.Command) bool {
+	reportPath, _ := cmd.Flags().GetString("report")
+	return reportPath == "-"
+}
+
+// Syncs an account repeatedly, keeping its Lectio browser and Google Calendar between syncs
+type accountSyncer struct {
+	account  accountConfig
+	opts     syncOptions
+	calendar *lectigo.GoogleCalendar
+	client   *http.Client
+	lectio   *lectigo.Lectio             // Started on the first sync, and again after a failed sync
+	scraped  map[string]lectigo.Module   // The modules scraped by the running sync
+	logger   *slog.Logger                // Logs of the account, recording its warnings in warnings
+	warnings *warningLog                 // The warnings logged by the running sync
+	phases   map[syncPhase]time.Duration // The time taken by each phase of the running sync
+}
+
+func newAccountSyncer(account accountConfig, client *http.Client, opts syncOptions) *accountSyncer {
+	warnings := &warningLog{}
+	return &accountSyncer{
+		account:  account,
+		client:   client,
+		opts:     opts,
+		logger:   recordWarnings(accountLogger(account), warnings),
+		warnings: warnings,
+	}
+}
+
+// Syncs the account and returns the outcome with the time taken by each phase and the logged warnings
+func (s *accountSyncer) run() accountResult {
+	result, err := s.sync()
+	return accountResult{account: s.account, result: result, err: err, phases: s.phases, warnings: s.warnings.take()}
+}
+
+// Scrapes the Lectio schedule of the account and updates its Google Calendar with it. The Lectio session is reused if
+// it has not expired since the last sync
+func (s *accountSyncer) sync() (*lectigo.SyncResult, error) {
+	startedAt := time.Now()
+	s.scraped = nil
+	s.phases = make(map[syncPhase]time.Duration)
+	s.warnings.take()
+	result, err := s.trySync()
+	s.recordRun(startedAt, result, err)
+	if err != nil {
+		// The browser may be in any state after a failure, so the next sync starts a new one
+		s.close()
+		return nil, err
+	}
 
-		if !strings.HasSuffix(tokenPath, ".json") {
-			tokenPath += ".json"
+	// The calendar is updated at this point, so failing notifications do not fail the sync. The notifier is called
+	// without changes too, to send the changes it held back during quiet hours
+	if s.opts.notifier != nil {
+		notifyStarted := time.Now()
+		err = s.opts.notifier.Notify(context.Background(), s.account.Name, result.Changes)
+		s.phases[phaseNotify] = time.Since(notifyStarted)
+		if err != nil {
+			s.logger.Error("Could not send notifications", "error", err)
 		}
+	}
+	return result, nil
+}
 
-		client, err := util.GetClient(config, tokenPath)
+func (s *accountSyncer) trySync() (*lectigo.SyncResult, error) {
+	var err error
+	if s.calendar == nil {
+		s.calendar, err = lectigo.NewGoogleCalendar(s.client, s.account.CalendarID)
 		if err != nil {
-			log.Fatalf("Could not get Google Calendar client: %v\n", err)
+			return nil, fmt.Errorf("could not create Google Calendar instance: %w", err)
 		}
+		s.calendar.Namespace = s.account.Namespace
+		s.calendar.Logger = s.logger
+	}
+
+	started := time.Now()
+	if s.lectio == nil {
+		s.lectio, err = newLectio(s.account, s.opts, s.logger)
+	} else {
+		err = s.lectio.EnsureLoggedIn()
+	}
+	s.phases[phaseLogin] = time.Since(started)
+	if err != nil {
+		return nil, &phaseError{phaseLogin, err}
+	}
+
+	opts, c := s.opts, s.calendar
+	started = time.Now()
+	lModules, err := scrapeModules(s.lectio, s.account, opts)
+	s.phases[phaseScrape] = time.Since(started)
+	s.scraped = lModules
+	if err != nil {
+		return nil, &phaseError{phaseScrape, err}
+	}
+
+	// The state store records the modules as scraped, and the calendar gets them as transformed
+	lModules = opts.transform.ApplyAll(lModules)
+
+	started = time.Now()
+	gEvents, err := c.GetEvents(opts.weeks)
+	s.phases[phaseEvents] = time.Since(started)
+	if err != nil {
+		return nil, &phaseError{phaseEvents, fmt.Errorf("could not get events from Google Calendar: %w", err)}
+	}
 
-		c, err := lectigo.NewGoogleCalendar(client, calendarID)
+	started = time.Now()
+	result, err := c.UpdateCalendar(lModules, gEvents, opts.hideCancelled)
+	s.phases[phaseUpdate] = time.Since(started)
+	if err != nil {
+		return nil, &phaseError{phaseUpdate, fmt.Errorf("could not update Google Calendar: %w", err)}
+	}
+	return result, nil
+}
+
+// Records the sync in the state store, if any. A failure to record does not fail the sync
+func (s *accountSyncer) recordRun(startedAt time.Time, result *lectigo.SyncResult, syncErr error) {
+	if s.opts.statePath == "" {
+		return
+	}
+
+	// The store can only be opened once at a time, so concurrent accounts take turns
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	store, err := state.Open(s.opts.statePath)
+	if err != nil {
+		s.logger.Error("Could not open state store", "error", err)
+		return
+	}
+	defer store.Close()
+
+	_, err = store.RecordRun(state.Run{
+		Account:    s.account.Name,
+		CalendarID: s.account.CalendarID,
+		StartedAt:  startedAt,
+		Weeks:      s.opts.weeks,
+	}, s.scraped, result, syncErr)
+	if err != nil {
+		s.logger.Error("Could not record sync", "error", err)
+	}
+}
+
+// Scrapes the schedule of the account, with exams and module details if enabled by the options. The modules scraped
+// before a failure are returned with the error
+func scrapeModules(l *lectigo.Lectio, account accountConfig, opts syncOptions) (map[string]lectigo.Module, error) {
+	modules, err := l.GetScheduleWeeks(opts.weeks, account.target())
+	if err != nil {
+		return nil, fmt.Errorf("could not get Lectio schedule: %w", err)
+	}
+
+	if opts.decodeClass && opts.learnTeams {
+		err = learnTeamTitles(l, modules, opts)
 		if err != nil {
-			log.Fatalf("Could not create Google Calendar instance: %v\n", err)
+			return modules, err
 		}
-		l, err := lectigo.NewLectio(&lectigo.LectioLoginInfo{
-			Username: username,
-			Password: password,
-			SchoolID: schoolID,
-		}, decodeClass)
+	}
+
+	// The exam overview only lists the exams of the logged in user, which do not belong in the schedule of others
+	if opts.exams && account.target().Type != lectigo.ScheduleSelf {
+		l.Logger.Warn("Exams are only added to your own schedule", "schedule", account.target().Type)
+	} else if opts.exams {
+		err = addExams(l, modules, opts)
 		if err != nil {
-			log.Fatalf("Could not create Lectio instance: %v\n", err)
+			return modules, fmt.Errorf("could not get exams: %w", err)
 		}
+	}
 
-		lModules, err := l.GetScheduleWeeks(weeks)
+	if opts.details {
+		cache, err := lectigo.LoadActivityCache(opts.detailsCachePath)
 		if err != nil {
-			log.Fatalf("Could not get Lectio schedule: %v\n", err)
+			return modules, fmt.Errorf("could not load activity cache: %w", err)
 		}
-		l.Cancel() // End browser instance
-		// check if browserdp can be stopped here
+		err = l.FetchModuleDetails(modules, cache)
+		if err != nil {
+			return modules, fmt.Errorf("could not get module details: %w", err)
+		}
+		err = cache.Save(opts.detailsCachePath)
+		if err != nil {
+			return modules, fmt.Errorf("could not save activity cache: %w", err)
+		}
+	}
+	return modules, nil
+}
+
+// Learns the titles of the teams of the modules the abbreviations can not decode, keeping them in the team cache
+func learnTeamTitles(l *lectigo.Lectio, modules map[string]lectigo.Module, opts syncOptions) error {
+	cache, err := lectigo.LoadTeamCache(opts.teamCachePath)
+	if err != nil {
+		return fmt.Errorf("could not load team cache: %w", err)
+	}
+	err = l.LearnTeamTitles(modules, cache)
+	if err != nil {
+		return fmt.Errorf("could not learn team titles: %w", err)
+	}
+	err = cache.Save(opts.teamCachePath)
+	if err != nil {
+		return fmt.Errorf("could not save team cache: %w", err)
+	}
+	return nil
+}
+
+// Starts a browser logged in to Lectio as the account with the abbreviations and blacklist of the options, logging
+// to logger
+func newLectio(account accountConfig, opts syncOptions, logger *slog.Logger) (*lectigo.Lectio, error) {
+	var auth lectigo.Authenticator = &lectigo.PasswordAuthenticator{SessionPath: account.SessionPath}
+	if account.Cookies != "" {
+		auth = &lectigo.CookieFileAuthenticator{Path: account.Cookies}
+	}
 
-		gEvents, err := c.GetEvents(weeks)
+	l, err := lectigo.NewLectio(&lectigo.LectioLoginInfo{
+		Username: account.Username,
+		Password: account.Password,
+		SchoolID: account.SchoolID,
+	}, auth, &opts.scrapeOptions, logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not create Lectio instance: %w", err)
+	}
+
+	if opts.decodeClass {
+		l.Abbreviations, err = lectigo.LoadAbbreviations(opts.abbreviations)
 		if err != nil {
-			log.Fatalf("Could not get events from Google Calendar: %v\n", err)
+			l.Cancel()
+			return nil, fmt.Errorf("could not load abbreviations: %w", err)
 		}
-		err = c.UpdateCalendar(lModules, gEvents, hideCancelled)
+	}
+	blacklist := opts.blacklist
+	if blacklist == nil {
+		blacklist, err = lectigo.LoadBlacklist(opts.blacklistPath)
 		if err != nil {
-			log.Fatalf("Could not update Google Calendar: %v\n", err)
+			l.Cancel()
+			return nil, fmt.Errorf("could not load blacklist: %w", err)
 		}
-	},
+	}
+	l.Blacklist, err = lectigo.BlacklistRules(*blacklist)
+	if err != nil {
+		l.Cancel()
+		return nil, err
+	}
+	l.Filter = opts.filter
+	if l.Filter == nil && opts.filtersPath != "" {
+		l.Filter, err = lectigo.LoadFilter(opts.filtersPath)
+		if err != nil {
+			l.Cancel()
+			return nil, fmt.Errorf("could not load filters: %w", err)
+		}
+	}
+	return l, nil
+}
+
+// Ends the browser instance of the account
+func (s *accountSyncer) close() {
+	if s.lectio != nil {
+		s.lectio.Cancel()
+		s.lectio = nil
+	}
+}
+
+// Adds the exams of the student within the synced weeks to the modules
+func addExams(l *lectigo.Lectio, modules map[string]lectigo.Module, opts syncOptions) error {
+	exams, err := l.GetExams()
+	if err != nil {
+		return err
+	}
+
+	// Only exams within the synced weeks are added, as events outside of them are not compared with the calendar
+	startDate, err := util.GetMonday()
+	if err != nil {
+		return err
+	}
+	endDate := startDate.AddDate(0, 0, 7*opts.weeks)
+
+	for _, exam := range exams {
+		if exam.StartDate.Before(startDate) || !exam.StartDate.Before(endDate) {
+			continue
+		}
+		modules[exam.Id] = exam.ToModule(opts.examReminders)
+	}
+	return nil
+}
+
+// Creates a Google Calendar HTTP client from the OAuth client credentials file and the OAuth token file. If the token
+// file does not exist and the secret store does, the token is kept in the store instead. The store is only opened then,
+// so its passphrase is not needed while the token file exists
+func newGoogleClient(cmd *cobra.Command, credentialsPath, tokenPath string) (*http.Client, error) {
+	// Reads the credentials file and creates a config from it - this is used to create the client
+	bytes, err := os.ReadFile(credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read contents of %s: %w", credentialsPath, err)
+	}
+
+	config, err := google.ConfigFromJSON(bytes, "https://www.googleapis.com/auth/calendar.calendarlist.readonly", "https://www.googleapis.com/auth/calendar.events")
+	if err != nil {
+		return nil, fmt.Errorf("could not create config from %s: %w", credentialsPath, err)
+	}
+
+	if !strings.HasSuffix(tokenPath, ".json") {
+		tokenPath += ".json"
+	}
+
+	if _, err := os.Stat(tokenPath); err != nil {
+		store, err := openSecretStore(cmd, false)
+		if err != nil {
+			return nil, fmt.Errorf("could not open secret store: %w", err)
+		}
+		if store != nil {
+			return util.GetClientFromStore(config, &util.SecretTokenStore{Store: store, Name: tokenSecretName(tokenPath)})
+		}
+	}
+	return util.GetClient(config, tokenPath)
 }
 
 func init() {
 	rootCmd.AddCommand(syncCmd)
+	addSyncFlags(syncCmd)
+
+	syncCmd.Flags().String("report", "", "The path to write a JSON report of the sync to, or - for stdout")
+	syncCmd.Flags().Bool("detailedExitCode", false, "Exit with 2 instead of 0 when the sync changed the calendar")
+}
+
+// Adds the flags of a sync to the command
+func addSyncFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("calendarID", "c", "primary", "Google Calendar calendar ID")
+	cmd.Flags().StringP("tokenPath", "t", "token.json", "The path to a Google OAuth token file")
+	cmd.Flags().String("credentials", "credentials.json", "The path to the Google OAuth client credentials")
+	cmd.Flags().Bool("hideCancelled", false, "Hide cancelled classes from the calendar")
+
+	// Without a user cache directory, syncs are only recorded when --state is given
+	statePath, _ := state.DefaultPath()
+	cmd.Flags().String("state", statePath, "The path to the local store recording the history of syncs. Leave empty to not record syncs")
+	cmd.Flags().String("notifications", "", "The path to a notifications file listing channels to send changed modules through")
+	cmd.Flags().String("transforms", "", "The path to a list of transform rules changing the titles, colours, reminders, visibility and descriptions of events")
+
+	cmd.Flags().String("accounts", "", "Sync several Lectio accounts listed in a YAML file instead of the account given by flags")
+
+	addLectioFlags(cmd)
+}
+
+// Adds the flags for logging in to Lectio and scraping a schedule to the command
+func addLectioFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("username", "u", "", "Lectio username (required unless --cookies is given)")
+	cmd.Flags().StringP("password", "p", "", "Lectio password")
+	cmd.Flags().MarkDeprecated("password", "it is visible in the shell history and to other users, use --passwordFile, "+lectioPasswordEnv+" or the secret store instead")
+	cmd.Flags().String("passwordFile", "", "The path to a file holding the Lectio password")
+	cmd.Flags().StringP("schoolID", "s", "", "Lectio school ID or school name (required unless --accounts is given)")
+	cmd.Flags().IntP("weeks", "w", 2, "Amount of weeks to sync")
+	cmd.Flags().Int("tabs", lectigo.DefaultTabs, "Amount of browser tabs fetching weeks from Lectio concurrently")
+	cmd.Flags().Float64("rps", lectigo.DefaultRequestsPerSecond, "Maximum amount of requests per second to Lectio (0 for no limit)")
+	cmd.Flags().Int("retries", lectigo.DefaultMaxRetries, "Amount of retries when Lectio fails or is temporarily unavailable")
+	cmd.Flags().Duration("retryBackoff", lectigo.DefaultRetryBackoff, "Wait before the first retry of a failed Lectio request. Doubles on every retry")
+	cmd.Flags().String("userAgent", "", "User-Agent sent to Lectio (default is the browser's own)")
+	cmd.Flags().String("sessionPath", "session.json", "The path to a file storing the Lectio session between runs. Leave empty to log in on every run")
+	cmd.Flags().String("cookies", "", "Log in with Lectio cookies exported from your browser (Netscape cookies.txt or JSON) instead of a password, eg. for MitID or UNI-Login schools")
+	cmd.Flags().Bool("details", false, "Fetch the activity page of every module for full notes, homework, materials and attachments")
+	cmd.Flags().String("detailsCache", "activitycache.json", "The path to the cache of fetched activity pages")
+	cmd.Flags().Bool("exams", false, "Add exams from the exam overview as highlighted events. Only for your own schedule")
+	cmd.Flags().IntSlice("examReminders", []int{24 * 60, 60}, "Reminders for exams in minutes before the start of the exam")
+	cmd.Flags().BoolP("decodeClass", "d", false, "Replace abbreviated classes with their real title")
+	cmd.Flags().String("abbreviations", "abbreviations.yml", "The path to the abbreviations of classes used by --decodeClass")
+	cmd.Flags().Bool("learnTeams", false, "Learn the titles of teams --decodeClass can not decode from their pages in Lectio")
+	cmd.Flags().String("teamCache", "teamcache.json", "The path to the cache of team titles learned by --learnTeams")
+	cmd.Flags().String("blacklist", "blacklist.yml", "The path to the list of classes to ignore")
+	cmd.Flags().String("filters", "", "The path to a list of filter rules including or excluding modules")
+
+	cmd.Flags().String("student", "", "Sync the schedule of the student with the given Lectio ID instead of your own")
+	cmd.Flags().String("teacher", "", "Sync the schedule of the teacher with the given Lectio ID instead of your own")
+	cmd.Flags().String("room", "", "Sync the schedule of the room with the given Lectio ID instead of your own")
+	cmd.Flags().String("class", "", "Sync the schedule of the class with the given Lectio ID instead of your own")
+	cmd.MarkFlagsMutuallyExclusive("student", "teacher", "room", "class")
+
+	cmd.MarkFlagsMutuallyExclusive("password", "passwordFile", "cookies")
+}
+
+// Returns the account given by the flags with its school ID and password resolved, and its Google Calendar client
+func prepareFlagAccount(cmd *cobra.Command, opts syncOptions) (accountConfig, *http.Client) {
+	account := lectioAccountFromFlags(cmd)
+
+	client, err := newGoogleClient(cmd, opts.credentialsPath, account.TokenPath)
+	if err != nil {
+		exitWith(exitCalendarAuthFailed, "Could not get Google Calendar client", "error", err)
+	}
+	return account, client
+}
+
+// Returns the account given by the flags with its school ID and password resolved
+func lectioAccountFromFlags(cmd *cobra.Command) accountConfig {
+	account := schoolAccountFromFlags(cmd)
+	err := resolvePassword(cmd, &account)
+	if err != nil {
+		fatal("Could not get Lectio password", "error", err)
+	}
+	return account
+}
+
+// Returns the account given by the flags with its school ID resolved
+func schoolAccountFromFlags(cmd *cobra.Command) accountConfig {
+	account := accountFromFlags(cmd)
+	if account.SchoolID == "" {
+		fatal("The --schoolID flag must be given")
+	}
+	schoolID, err := resolveSchoolID(cmd, account.SchoolID)
+	if err != nil {
+		fatal("Could not find school", "error", err)
+	}
+	account.SchoolID = schoolID
+	if account.Cookies == "" && account.Username == "" {
+		fatal("Either --username or --cookies must be given")
+	}
+	return account
+}
+
+// Returns the account given by the flags
+func accountFromFlags(cmd *cobra.Command) accountConfig {
+	var account accountConfig
+	account.Username, _ = cmd.Flags().GetString("username")
+	account.Password, _ = cmd.Flags().GetString("password")
+	account.PasswordFile, _ = cmd.Flags().GetString("passwordFile")
+	account.PasswordEnv = lectioPasswordEnv
+	account.SchoolID, _ = cmd.Flags().GetString("schoolID")
+	account.Cookies, _ = cmd.Flags().GetString("cookies")
+	account.SessionPath, _ = cmd.Flags().GetString("sessionPath")
+	account.CalendarID, _ = cmd.Flags().GetString("calendarID")
+	account.TokenPath, _ = cmd.Flags().GetString("tokenPath")
+	account.Student, _ = cmd.Flags().GetString("student")
+	account.Teacher, _ = cmd.Flags().GetString("teacher")
+	account.Room, _ = cmd.Flags().GetString("room")
+	account.Class, _ = cmd.Flags().GetString("class")
+	return account
+}
+
+// Returns the options shared by all accounts given by the flags
+func syncOptionsFromFlags(cmd *cobra.Command) syncOptions {
+	var opts syncOptions
+	opts.weeks, _ = cmd.Flags().GetInt("weeks")
+	opts.hideCancelled, _ = cmd.Flags().GetBool("hideCancelled")
+	opts.decodeClass, _ = cmd.Flags().GetBool("decodeClass")
+	opts.abbreviations, _ = cmd.Flags().GetString("abbreviations")
+	opts.learnTeams, _ = cmd.Flags().GetBool("learnTeams")
+	opts.teamCachePath, _ = cmd.Flags().GetString("teamCache")
+	opts.blacklistPath, _ = cmd.Flags().GetString("blacklist")
+	opts.blacklist = activeProfile.blacklist
+	opts.filtersPath, _ = cmd.Flags().GetString("filters")
+	opts.filter = activeProfile.filter
+	opts.credentialsPath, _ = cmd.Flags().GetString("credentials")
+	opts.details, _ = cmd.Flags().GetBool("details")
+	opts.detailsCachePath, _ = cmd.Flags().GetString("detailsCache")
+	opts.exams, _ = cmd.Flags().GetBool("exams")
+	opts.examReminders, _ = cmd.Flags().GetIntSlice("examReminders")
+	opts.scrapeOptions = scrapeOptionsFromFlags(cmd)
+	opts.statePath, _ = cmd.Flags().GetString("state")
+
+	opts.transform = activeProfile.transform
+	transformsPath, _ := cmd.Flags().GetString("transforms")
+	if opts.transform == nil && transformsPath != "" {
+		var err error
+		opts.transform, err = lectigo.LoadTransform(transformsPath)
+		if err != nil {
+			fatal("Could not load transforms", "error", err)
+		}
+	}
+
+	notificationsPath, _ := cmd.Flags().GetString("notifications")
+	if notificationsPath != "" {
+		var err error
+		opts.notifier, err = loadNotifications(notificationsPath)
+		if err != nil {
+			fatal("Could not load notifications", "error", err)
+		}
+	}
+	return opts
+}
 
-	syncCmd.Flags().StringP("username", "u", "", "Lectio username (required)")
-	syncCmd.Flags().StringP("password", "p", "", "Lectio password (required)")
-	syncCmd.Flags().StringP("schoolID", "s", "", "Lectio school ID (required)")
-	syncCmd.Flags().IntP("weeks", "w", 2, "Amount of weeks to sync")
-	syncCmd.Flags().StringP("calendarID", "c", "primary", "Google Calendar calendar ID")
-	syncCmd.Flags().StringP("tokenPath", "t", "token.json", "The path to a Google OAuth token file")
-	syncCmd.Flags().Bool("hideCancelled", false, "Hide cancelled classes from the calendar")
-	syncCmd.Flags().BoolP("decodeClass", "d", false, "Replace abbreviated classes with their real title")
-
-	syncCmd.MarkFlagRequired("username")
-	syncCmd.MarkFlagRequired("password")
-	syncCmd.MarkFlagRequired("schoolID")
+// Returns the options for requests to Lectio given by the flags
+func scrapeOptionsFromFlags(cmd *cobra.Command) lectigo.ScrapeOptions {
+	opts := lectigo.DefaultScrapeOptions()
+	opts.Tabs, _ = cmd.Flags().GetInt("tabs")
+	opts.RequestsPerSecond, _ = cmd.Flags().GetFloat64("rps")
+	opts.MaxRetries, _ = cmd.Flags().GetInt("retries")
+	opts.RetryBackoff, _ = cmd.Flags().GetDuration("retryBackoff")
+	opts.UserAgent, _ = cmd.Flags().GetString("userAgent")
+	return opts
 }
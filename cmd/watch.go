@@ -0,0 +1,185 @@
+/*
+Copyright © 2023 Mattis Kristensen <mattismoel@gmail.com>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/util"
+	"github.com/spf13/cobra"
+)
+
+// The longest wait before retrying a failed sync
+const maxFailureBackoff = time.Hour
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Keeps syncing a Lectio schedule with a Google Calendar periodically",
+	Long: `Keeps running and syncs the Lectio schedule with Google Calendar periodically, either at a fixed --interval or at
+the times of one or more --cron expressions (minute, hour, day of month, month, day of week). The browser logged in to
+Lectio and the Google OAuth client are kept between syncs.
+
+A failed sync is retried after --failureBackoff instead of at the next scheduled sync, doubling on every failure up to an
+hour, eg. while Lectio is down for maintenance. On SIGINT or
+SIGTERM a running sync is finished before shutting down, so the calendar is never left half updated.
+
+Examples:
+
+	lego watch -u username1234 -s 133 --interval 30m
+	lego watch -u username1234 -s 133 --cron "*/10 7-8 * * 1-5" --cron "0 * * * *"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		schedule, err := scheduleFromFlags(cmd)
+		if err != nil {
+			fatal("Could not parse schedule", "error", err)
+		}
+		failureBackoff, _ := cmd.Flags().GetDuration("failureBackoff")
+
+		accountsPath, _ := cmd.Flags().GetString("accounts")
+		opts := syncOptionsFromFlags(cmd)
+
+		var syncers []*accountSyncer
+		if accountsPath != "" {
+			syncers = prepareAccounts(cmd, accountsPath, opts)
+		} else {
+			account, client := prepareFlagAccount(cmd, opts)
+			syncers = []*accountSyncer{newAccountSyncer(account, client, opts)}
+		}
+		defer closeSyncers(syncers)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		watch(ctx, stop, syncers, accountsPath != "", schedule, failureBackoff)
+		slog.Info("Stopped watching")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	addSyncFlags(watchCmd)
+
+	watchCmd.Flags().Duration("interval", time.Hour, "The time between syncs")
+	watchCmd.Flags().StringArray("cron", nil, "A cron expression of when to sync, eg. \"*/10 7-8 * * 1-5\". Can be given more than once")
+	watchCmd.Flags().Duration("failureBackoff", 5*time.Minute, "Wait before retrying a failed sync. Doubles on every failure up to an hour")
+	watchCmd.MarkFlagsMutuallyExclusive("interval", "cron")
+}
+
+// Returns the schedule given by either --cron or --interval. An --interval on the command line overrides a profile's cron
+func scheduleFromFlags(cmd *cobra.Command) (util.Schedule, error) {
+	expressions, _ := cmd.Flags().GetStringArray("cron")
+	if len(expressions) > 0 && !cmd.Flags().Changed("interval") {
+		var schedule util.MultiSchedule
+		for _, expr := range expressions {
+			cron, err := util.ParseCron(expr)
+			if err != nil {
+				return nil, err
+			}
+			schedule = append(schedule, cron)
+		}
+		return schedule, nil
+	}
+
+	interval, _ := cmd.Flags().GetDuration("interval")
+	if interval < time.Minute {
+		return nil, fmt.Errorf("the interval must be at least a minute, not %v", interval)
+	}
+	return util.IntervalSchedule(interval), nil
+}
+
+// Syncs the accounts at the times of the schedule until ctx is cancelled. A running sync is always finished, and stop
+// is called on cancellation so a second signal ends the process at once
+func watch(ctx context.Context, stop func(), syncers []*accountSyncer, summary bool, schedule util.Schedule, failureBackoff time.Duration) {
+	backoff := time.Duration(0)
+	next := time.Now()
+
+	for {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		slog.Info("Syncing Lectio and Google Calendar")
+		done := make(chan []accountResult, 1)
+		go func() {
+			done <- syncAll(syncers)
+		}()
+
+		var results []accountResult
+		select {
+		case results = <-done:
+		case <-ctx.Done():
+			stop()
+			slog.Info("Finishing the running sync before shutting down. Interrupt again to quit at once")
+			results = <-done
+		}
+		failed := printWatchResults(results, summary)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		now := time.Now()
+		scheduled := schedule.Next(now)
+		if scheduled.IsZero() {
+			slog.Info("The schedule has no more syncs")
+			return
+		}
+
+		if failed == nil {
+			backoff = 0
+			next = scheduled
+		} else {
+			backoff = nextBackoff(backoff, failureBackoff)
+			next = now.Add(backoff)
+			if errors.Is(failed, lectigo.ErrLectioUnavailable) {
+				slog.Warn("Lectio is unavailable")
+			}
+		}
+		slog.Info("Waiting for the next sync", "next", next.Format(time.DateTime))
+	}
+}
+
+// Doubles the backoff, starting at first and ending at maxFailureBackoff
+func nextBackoff(backoff, first time.Duration) time.Duration {
+	if backoff == 0 {
+		return first
+	}
+	return min(backoff*2, max(first, maxFailureBackoff))
+}
+
+// Prints the outcome of a sync and returns the error of the first failed account, if any
+func printWatchResults(results []accountResult, summary bool) error {
+	var failed error
+	for _, r := range results {
+		if r.err != nil && failed == nil {
+			failed = r.err
+		}
+	}
+
+	if summary {
+		printAccountResults(results)
+		return failed
+	}
+
+	for _, r := range results {
+		if r.err != nil {
+			accountLogger(r.account).Error("Could not sync", "error", r.err)
+			continue
+		}
+		fmt.Println(r.result)
+	}
+	return failed
+}
@@ -0,0 +1,164 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+)
+
+// The modules of a single day of an agenda
+type agendaDay struct {
+	date    time.Time
+	modules []lectigo.Module
+}
+
+// Groups the modules, sorted by start time, by the day they start on
+func groupByDay(modules []lectigo.Module) []agendaDay {
+	var days []agendaDay
+	for _, m := range modules {
+		y, mo, d := m.StartDate.Date()
+		date := time.Date(y, mo, d, 0, 0, 0, 0, m.StartDate.Location())
+		if len(days) == 0 || !days[len(days)-1].date.Equal(date) {
+			days = append(days, agendaDay{date: date})
+		}
+		days[len(days)-1].modules = append(days[len(days)-1].modules, m)
+	}
+	return days
+}
+
+// Returns the time of the module as shown in an agenda, eg. "08:00-09:30" or "All day"
+func agendaTime(m *lectigo.Module) string {
+	if !m.AllDay {
+		return m.StartDate.Format("15:04") + "-" + m.EndDate.Format("15:04")
+	}
+	// The end of all-day modules is exclusive
+	last := m.EndDate.AddDate(0, 0, -1)
+	if last.After(m.StartDate) {
+		return "All day until " + last.Format("Mon 2 Jan")
+	}
+	return "All day"
+}
+
+// Returns the details of the module shown after its title, eg. "22 · ABC"
+func agendaDetails(m *lectigo.Module) []string {
+	var details []string
+	if r := m.RoomNames(); r != "" {
+		details = append(details, r)
+	}
+	if t := m.TeacherInitials(); t != "" {
+		details = append(details, t)
+	}
+	if m.ModuleStatus != lectigo.StatusNormal {
+		details = append(details, string(m.ModuleStatus))
+	}
+	if m.Exam {
+		details = append(details, "exam")
+	}
+	return details
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, "`", "\\`")
+
+func writeMarkdown(w io.Writer, modules []lectigo.Module) error {
+	for i, day := range groupByDay(modules) {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "## %s\n\n", day.date.Format("Monday 2 January 2006"))
+		for _, m := range day.modules {
+			title := markdownEscaper.Replace(m.Title)
+			if m.ModuleStatus == lectigo.StatusCancelled {
+				title = "~~" + title + "~~"
+			}
+			fmt.Fprintf(w, "- **%s** %s", agendaTime(&m), title)
+			if details := agendaDetails(&m); len(details) > 0 {
+				fmt.Fprintf(w, " (%s)", markdownEscaper.Replace(strings.Join(details, " · ")))
+			}
+			fmt.Fprintln(w)
+			writeMarkdownNote(w, "Homework", m.Homework)
+			writeMarkdownNote(w, "Note", m.Description)
+		}
+	}
+	return nil
+}
+
+// Writes a note of a module as an indented list item
+func writeMarkdownNote(w io.Writer, label, note string) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return
+	}
+	lines := strings.Split(note, "\n")
+	fmt.Fprintf(w, "  - %s: %s", label, markdownEscaper.Replace(lines[0]))
+	for _, line := range lines[1:] {
+		fmt.Fprintf(w, "  \n    %s", markdownEscaper.Replace(line))
+	}
+	fmt.Fprintln(w)
+}
+
+func writeOrg(w io.Writer, modules []lectigo.Module) error {
+	for _, day := range groupByDay(modules) {
+		fmt.Fprintf(w, "* %s\n", day.date.Format("Monday 2 January 2006"))
+		for _, m := range day.modules {
+			fmt.Fprintf(w, "** %s", orgText(m.Title))
+			var tags []string
+			if m.ModuleStatus != lectigo.StatusNormal {
+				tags = append(tags, string(m.ModuleStatus))
+			}
+			if m.Exam {
+				tags = append(tags, "exam")
+			}
+			if len(tags) > 0 {
+				fmt.Fprintf(w, " :%s:", strings.Join(tags, ":"))
+			}
+			fmt.Fprintln(w)
+
+			fmt.Fprintf(w, "   %s\n", orgTimestamp(&m))
+			fmt.Fprintln(w, "   :PROPERTIES:")
+			fmt.Fprintf(w, "   :LECTIO_ID: %s\n", m.Id)
+			if r := m.RoomNames(); r != "" {
+				fmt.Fprintf(w, "   :LOCATION: %s\n", orgText(r))
+			}
+			if t := m.TeacherInitials(); t != "" {
+				fmt.Fprintf(w, "   :TEACHER: %s\n", orgText(t))
+			}
+			fmt.Fprintln(w, "   :END:")
+			writeOrgNote(w, "Homework", m.Homework)
+			writeOrgNote(w, "Note", m.Description)
+		}
+	}
+	return nil
+}
+
+// Returns an active Org timestamp of the module, eg. "<2026-10-19 Mon 08:00-09:30>"
+func orgTimestamp(m *lectigo.Module) string {
+	if !m.AllDay {
+		return fmt.Sprintf("<%s-%s>", m.StartDate.Format("2006-01-02 Mon 15:04"), m.EndDate.Format("15:04"))
+	}
+	start := fmt.Sprintf("<%s>", m.StartDate.Format("2006-01-02 Mon"))
+	last := m.EndDate.AddDate(0, 0, -1)
+	if last.After(m.StartDate) {
+		return fmt.Sprintf("%s--<%s>", start, last.Format("2006-01-02 Mon"))
+	}
+	return start
+}
+
+// Writes a note of a module as a paragraph, keeping its lines from being read as headlines
+func writeOrgNote(w io.Writer, label, note string) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return
+	}
+	fmt.Fprintf(w, "   %s:\n", label)
+	for _, line := range strings.Split(note, "\n") {
+		fmt.Fprintf(w, "   %s\n", orgText(line))
+	}
+}
+
+// Keeps text on a single line of an Org file
+func orgText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,82 @@
+// Package export writes scraped modules and the schools of Lectio in formats like JSON, CSV, Markdown and iCalendar.
+// Formats are kept in a registry shared by every command writing modules or schools
+package export
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/util"
+)
+
+// A format modules or schools can be written in. A format supports modules, schools or both
+type Format struct {
+	Name      string
+	Extension string                                            // The file extension of the format, without the dot
+	Modules   func(w io.Writer, modules []lectigo.Module) error // Writes modules. Nil if the format does not support modules
+	Schools   func(w io.Writer, schools []util.School) error    // Writes schools. Nil if the format does not support schools
+}
+
+var formats = make(map[string]*Format)
+
+// Adds a format to the registry, replacing any format of the same name
+func Register(format *Format) {
+	formats[strings.ToLower(format.Name)] = format
+}
+
+// Returns the registered format with the given name
+func Lookup(name string) (*Format, error) {
+	format, ok := formats[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", name)
+	}
+	return format, nil
+}
+
+// Returns the names of the formats supporting modules
+func ModuleFormats() []string {
+	return names(func(f *Format) bool { return f.Modules != nil })
+}
+
+// Returns the names of the formats supporting schools
+func SchoolFormats() []string {
+	return names(func(f *Format) bool { return f.Schools != nil })
+}
+
+func names(keep func(f *Format) bool) []string {
+	var names []string
+	for name, format := range formats {
+		if keep(format) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Writes the modules in the named format
+func WriteModules(w io.Writer, name string, modules []lectigo.Module) error {
+	format, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	if format.Modules == nil {
+		return fmt.Errorf("format %q does not support modules (use %s)", name, strings.Join(ModuleFormats(), ", "))
+	}
+	return format.Modules(w, modules)
+}
+
+// Writes the schools in the named format
+func WriteSchools(w io.Writer, name string, schools []util.School) error {
+	format, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	if format.Schools == nil {
+		return fmt.Errorf("format %q does not support schools (use %s)", name, strings.Join(SchoolFormats(), ", "))
+	}
+	return format.Schools(w, schools)
+}
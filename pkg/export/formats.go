@@ -0,0 +1,113 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	"github.com/mattismoel/lectigo/util"
+)
+
+func init() {
+	Register(&Format{
+		Name:      "json",
+		Extension: "json",
+		Modules:   func(w io.Writer, modules []lectigo.Module) error { return writeJSON(w, modules) },
+		Schools:   func(w io.Writer, schools []util.School) error { return writeJSON(w, schools) },
+	})
+	Register(&Format{
+		Name:      "yaml",
+		Extension: "yaml",
+		Modules:   func(w io.Writer, modules []lectigo.Module) error { return yaml.NewEncoder(w).Encode(modules) },
+		Schools:   func(w io.Writer, schools []util.School) error { return yaml.NewEncoder(w).Encode(schools) },
+	})
+	Register(&Format{
+		Name:      "xml",
+		Extension: "xml",
+		Schools:   writeSchoolsXML,
+	})
+	Register(&Format{
+		Name:      "csv",
+		Extension: "csv",
+		Modules:   writeModulesCSV,
+		Schools:   writeSchoolsCSV,
+	})
+	Register(&Format{
+		Name:      "markdown",
+		Extension: "md",
+		Modules:   writeMarkdown,
+	})
+	Register(&Format{
+		Name:      "org",
+		Extension: "org",
+		Modules:   writeOrg,
+	})
+	Register(&Format{
+		Name:      "ical",
+		Extension: "ics",
+		Modules:   writeICal,
+	})
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writeSchoolsXML(w io.Writer, schools []util.School) error {
+	type XMLSchools struct {
+		XMLName xml.Name      `xml:"schools"`
+		Schools []util.School `xml:"school"`
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err := encoder.Encode(&XMLSchools{Schools: schools})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w)
+	return err
+}
+
+func writeSchoolsCSV(w io.Writer, schools []util.School) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"schoolID", "name"})
+	for _, school := range schools {
+		cw.Write([]string{school.SchoolID, school.Name})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeModulesCSV(w io.Writer, modules []lectigo.Module) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "title", "start", "end", "allDay", "rooms", "teachers", "group", "teams", "status", "homework", "note", "exam", "holiday"})
+	for _, m := range modules {
+		cw.Write([]string{
+			m.Id,
+			m.Title,
+			m.StartDate.Format(time.RFC3339),
+			m.EndDate.Format(time.RFC3339),
+			strconv.FormatBool(m.AllDay),
+			m.RoomNames(),
+			m.TeacherInitials(),
+			m.Group,
+			strings.Join(m.Teams, ", "),
+			string(m.ModuleStatus),
+			m.Homework,
+			m.Description,
+			strconv.FormatBool(m.Exam),
+			strconv.FormatBool(m.Holiday),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,86 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+)
+
+// The longest line of an iCalendar file in octets, excluding the line break
+const icalLineLength = 75
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// Writes the modules as an iCalendar (RFC 5545) file
+func writeICal(w io.Writer, modules []lectigo.Module) error {
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	writeICalLine(bw, "BEGIN:VCALENDAR")
+	writeICalLine(bw, "VERSION:2.0")
+	writeICalLine(bw, "PRODID:-//lectigo//lectigo//EN")
+	writeICalLine(bw, "CALSCALE:GREGORIAN")
+	for _, m := range modules {
+		writeICalLine(bw, "BEGIN:VEVENT")
+		writeICalLine(bw, "UID:"+m.Id+"@lectigo")
+		writeICalLine(bw, "DTSTAMP:"+stamp)
+		if m.AllDay {
+			writeICalLine(bw, "DTSTART;VALUE=DATE:"+m.StartDate.Format("20060102"))
+			writeICalLine(bw, "DTEND;VALUE=DATE:"+m.EndDate.Format("20060102"))
+		} else {
+			writeICalLine(bw, "DTSTART:"+m.StartDate.UTC().Format("20060102T150405Z"))
+			writeICalLine(bw, "DTEND:"+m.EndDate.UTC().Format("20060102T150405Z"))
+		}
+		writeICalLine(bw, "SUMMARY:"+icalEscaper.Replace(m.Title))
+		if r := m.RoomNames(); r != "" {
+			writeICalLine(bw, "LOCATION:"+icalEscaper.Replace(r))
+		}
+		if description := strings.TrimSpace(m.EventDescription()); description != "" {
+			writeICalLine(bw, "DESCRIPTION:"+icalEscaper.Replace(description))
+		}
+		if m.ModuleStatus == lectigo.StatusCancelled {
+			writeICalLine(bw, "STATUS:CANCELLED")
+		} else {
+			writeICalLine(bw, "STATUS:CONFIRMED")
+		}
+		if m.Holiday {
+			writeICalLine(bw, "TRANSP:TRANSPARENT")
+		}
+		if m.Exam {
+			writeICalLine(bw, "CATEGORIES:Exam")
+		}
+		for _, minutes := range m.Reminders {
+			writeICalLine(bw, "BEGIN:VALARM")
+			writeICalLine(bw, "ACTION:DISPLAY")
+			writeICalLine(bw, "DESCRIPTION:"+icalEscaper.Replace(m.Title))
+			writeICalLine(bw, fmt.Sprintf("TRIGGER:-PT%dM", minutes))
+			writeICalLine(bw, "END:VALARM")
+		}
+		writeICalLine(bw, "END:VEVENT")
+	}
+	writeICalLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// Writes a content line, folding it into lines of at most 75 octets without splitting characters
+func writeICalLine(w *bufio.Writer, line string) {
+	limit := icalLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards their length
+		limit = icalLineLength - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,196 @@
+package lectigo
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The titles of the subject codes used in team names by most Danish gymnasiums
+var DefaultSubjects = map[string]string{
+	"AP":  "Almen sprogforståelse",
+	"AS":  "Astronomi",
+	"BI":  "Biologi",
+	"BK":  "Billedkunst",
+	"BT":  "Bioteknologi",
+	"DA":  "Dansk",
+	"DE":  "Design",
+	"DHO": "Dansk-historieopgave",
+	"DR":  "Dramatik",
+	"EN":  "Engelsk",
+	"FI":  "Filosofi",
+	"FR":  "Fransk",
+	"FY":  "Fysik",
+	"GE":  "Geovidenskab",
+	"GR":  "Græsk",
+	"HI":  "Historie",
+	"ID":  "Idræt",
+	"IT":  "Informationsteknologi",
+	"KE":  "Kemi",
+	"KI":  "Kinesisk",
+	"LA":  "Latin",
+	"MA":  "Matematik",
+	"MD":  "Mediefag",
+	"MU":  "Musik",
+	"NG":  "Naturgeografi",
+	"NV":  "Naturvidenskabeligt grundforløb",
+	"OL":  "Oldtidskundskab",
+	"PR":  "Programmering",
+	"PS":  "Psykologi",
+	"RE":  "Religion",
+	"SA":  "Samfundsfag",
+	"SP":  "Spansk",
+	"SRP": "Studieretningsprojekt",
+	"TY":  "Tysk",
+}
+
+// Decodes abbreviated team names (eg. "2a MA") into the titles of their subjects (eg. "Matematik")
+type Abbreviations struct {
+	Teams    map[string]string // Titles of whole team names, eg. "2a MA". Checked first
+	Subjects map[string]string // Titles of subject codes in upper case, eg. "MA". Checked before DefaultSubjects
+	Learned  map[string]string // Titles of whole team names learned from the team pages in Lectio
+}
+
+// The parts of a team name following the naming of teams in Lectio, eg. "2a MA", "3g FyB" or "1x TyF2"
+type TeamName struct {
+	Class   string // The class of the team, eg. "2a". Empty for teams across classes
+	Subject string // The subject code in upper case, eg. "MA"
+	Level   string // The level of the subject (A, B or C), if part of the name
+	Suffix  string // The rest of the name, eg. the number of a team of a subject with several teams
+}
+
+// Reads abbreviations from a YAML file. The file either maps subject codes and team names to titles under the
+// subjects and teams keys, or maps team names to titles directly
+func LoadAbbreviations(path string) (*Abbreviations, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	abbreviations, err := ParseAbbreviations(b)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse abbreviations file %q: %w", path, err)
+	}
+	return abbreviations, nil
+}
+
+// Parses abbreviations in the format of LoadAbbreviations
+func ParseAbbreviations(b []byte) (*Abbreviations, error) {
+	var keys map[string]any
+	err := yaml.Unmarshal(b, &keys)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Abbreviations{}
+	_, hasSubjects := keys["subjects"]
+	_, hasTeams := keys["teams"]
+	if hasSubjects || hasTeams {
+		var file struct {
+			Subjects map[string]string `yaml:"subjects"`
+			Teams    map[string]string `yaml:"teams"`
+		}
+		err = yaml.Unmarshal(b, &file)
+		if err != nil {
+			return nil, err
+		}
+		a.Teams = file.Teams
+		a.Subjects = make(map[string]string, len(file.Subjects))
+		for code, title := range file.Subjects {
+			a.Subjects[strings.ToUpper(code)] = title
+		}
+	} else {
+		err = yaml.Unmarshal(b, &a.Teams)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Returns the title of the team, or false if the team can not be decoded. Nil abbreviations decode nothing
+func (a *Abbreviations) Decode(team string) (string, bool) {
+	if a == nil {
+		return "", false
+	}
+	if title, ok := lookupTeam(a.Teams, team); ok {
+		return title, true
+	}
+	if title, ok := lookupTeam(a.Learned, team); ok {
+		return title, true
+	}
+
+	name := a.ParseTeam(team)
+	if title := a.Subjects[name.Subject]; title != "" {
+		return title, true
+	}
+	if title := DefaultSubjects[name.Subject]; title != "" {
+		return title, true
+	}
+	return "", false
+}
+
+// Returns the non-empty title of the team in the map, preferring an exact match over one ignoring case
+func lookupTeam(titles map[string]string, team string) (string, bool) {
+	if title := titles[team]; title != "" {
+		return title, true
+	}
+	for name, title := range titles {
+		if title != "" && strings.EqualFold(name, team) {
+			return title, true
+		}
+	}
+	return "", false
+}
+
+// The rest of a team name following its subject code, eg. "B" of "FyB", "F2" of "TyF2" or "A1x" of "MaA1x"
+var teamSuffix = regexp.MustCompile(`^\pL?(\d+\pL?)?$`)
+
+// Splits a team name into its parts. The subject code is the longest known subject code the name starts with and is
+// followed by a level or a team suffix, or else the letters the name starts with. Names merely starting with a subject
+// code, eg. "Projektuge", are not taken for the subject
+func (a *Abbreviations) ParseTeam(team string) TeamName {
+	var name TeamName
+	fields := strings.Fields(team)
+	if len(fields) > 1 && strings.ContainsFunc(fields[0], unicode.IsDigit) {
+		name.Class, fields = fields[0], fields[1:]
+	}
+	code := strings.ToUpper(strings.Join(fields, ""))
+
+	isSubject := func(subject string) bool {
+		return len(subject) > len(name.Subject) && strings.HasPrefix(code, subject) &&
+			teamSuffix.MatchString(code[len(subject):])
+	}
+	for subject := range DefaultSubjects {
+		if isSubject(subject) {
+			name.Subject = subject
+		}
+	}
+	if a != nil {
+		for subject := range a.Subjects {
+			if isSubject(subject) {
+				name.Subject = subject
+			}
+		}
+	}
+
+	if name.Subject == "" {
+		end := strings.IndexFunc(code, func(r rune) bool { return !unicode.IsLetter(r) })
+		if end < 0 {
+			end = len(code)
+		}
+		name.Subject, name.Suffix = code[:end], code[end:]
+		return name
+	}
+
+	rest := code[len(name.Subject):]
+	if len(rest) > 0 && strings.ContainsRune("ABC", rune(rest[0])) && (len(rest) == 1 || unicode.IsDigit(rune(rest[1]))) {
+		name.Level, rest = rest[:1], rest[1:]
+	}
+	name.Suffix = rest
+	return name
+}
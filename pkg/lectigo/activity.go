@@ -0,0 +1,333 @@
+package lectigo
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"golang.org/x/net/html"
+)
+
+const (
+	activityCacheMaxAge = 90 * 24 * time.Hour // How long cached activity details are kept after they were last fetched
+	// How long cached details of upcoming modules are used. Attachments and materials can be added to the activity page
+	// without changing the module in the schedule, so the pages of upcoming modules are fetched again now and then
+	activityRefreshAge = 6 * time.Hour
+	activityUpcoming   = 7 * 24 * time.Hour // How far ahead modules are upcoming
+)
+
+// A file or link attached to a module by the teacher
+type Attachment struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// The details of a module found on its activity page
+type ActivityDetails struct {
+	Description string       `json:"description"` // The full note of the teacher
+	Homework    string       `json:"homework"`    // The full homework
+	Materials   string       `json:"materials"`   // Other content ("Øvrigt indhold") of the module
+	Attachments []Attachment `json:"attachments"` // Files and links attached to the module
+}
+
+// A cache of activity details, so that activity pages of unchanged modules are not fetched again
+type ActivityCache struct {
+	Entries map[string]ActivityCacheEntry `json:"entries"`
+
+	mu sync.Mutex
+}
+
+type ActivityCacheEntry struct {
+	Fingerprint string          `json:"fingerprint"` // Fingerprint of the module as shown in the schedule when the details were fetched
+	FetchedAt   time.Time       `json:"fetchedAt"`
+	Details     ActivityDetails `json:"details"`
+}
+
+// Reads an activity cache from a file. A missing file results in an empty cache
+func LoadActivityCache(path string) (*ActivityCache, error) {
+	cache := &ActivityCache{Entries: make(map[string]ActivityCacheEntry)}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, cache)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse activity cache %q: %w", path, err)
+	}
+	if cache.Entries == nil {
+		cache.Entries = make(map[string]ActivityCacheEntry)
+	}
+	return cache, nil
+}
+
+// Writes the cache to a file, leaving out entries that have not been fetched for a long time
+func (c *ActivityCache) Save(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, entry := range c.Entries {
+		if time.Since(entry.FetchedAt) > activityCacheMaxAge {
+			delete(c.Entries, id)
+		}
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// Returns the cached details of the module, unless it has changed in the schedule since, or it is upcoming and its
+// details are older than activityRefreshAge
+func (c *ActivityCache) get(id string, m *Module, now time.Time) (ActivityDetails, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.Entries[id]
+	if !ok || entry.Fingerprint != m.fingerprint() {
+		return entry.Details, false
+	}
+	upcoming := m.EndDate.After(now) && m.StartDate.Before(now.Add(activityUpcoming))
+	return entry.Details, !upcoming || now.Sub(entry.FetchedAt) < activityRefreshAge
+}
+
+func (c *ActivityCache) put(id, fingerprint string, details ActivityDetails) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Entries[id] = ActivityCacheEntry{
+		Fingerprint: fingerprint,
+		FetchedAt:   time.Now(),
+		Details:     details,
+	}
+}
+
+// Fetches the activity pages of the modules and fills their full description, homework, materials and attachments
+// into them. Modules that have not changed in the schedule since they were cached are filled from the cache instead,
+// unless they are within the coming week and were cached more than a few hours ago. Modules whose page can not be
+// fetched keep the values of the schedule. The cache may be nil
+func (l *Lectio) FetchModuleDetails(modules map[string]Module, cache *ActivityCache) error {
+	if cache == nil {
+		cache = &ActivityCache{Entries: make(map[string]ActivityCacheEntry)}
+	}
+
+	var toFetch []string
+	now := time.Now()
+	for id, module := range modules {
+		if !module.hasActivityPage() {
+			continue
+		}
+		if details, ok := cache.get(id, &module, now); ok {
+			module.applyDetails(details)
+			modules[id] = module
+			continue
+		}
+		toFetch = append(toFetch, id)
+	}
+
+	l.logger().Debug("Fetching activity pages", "pages", len(toFetch))
+	fetched := make([]*ActivityDetails, len(toFetch))
+	err := l.runInTabs(len(toFetch), func(ctx context.Context, i int) error {
+		details, err := l.getActivityDetails(ctx, toFetch[i])
+		if errors.Is(err, ErrLectioUnavailable) {
+			return err
+		}
+		// A single failing page keeps the values of the schedule, and is fetched again on the next sync
+		if err != nil {
+			l.logger().Warn("Could not get activity page", "module", toFetch[i], "error", err)
+			return nil
+		}
+		fetched[i] = &details
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not get activity pages: %w", err)
+	}
+
+	for i, id := range toFetch {
+		if fetched[i] == nil {
+			continue
+		}
+		module := modules[id]
+		cache.put(id, module.fingerprint(), *fetched[i])
+		module.applyDetails(*fetched[i])
+		modules[id] = module
+	}
+	return nil
+}
+
+// Fetches and parses the activity page of a module
+func (l *Lectio) getActivityDetails(ctx context.Context, id string) (ActivityDetails, error) {
+	pageUrl := fmt.Sprintf("https://www.lectio.dk/lectio/%s/aktivitet/aktivitetforside2.aspx?absid=%s", l.LoginInfo.SchoolID, url.QueryEscape(id))
+	err := l.navigate(ctx, pageUrl)
+	if err != nil {
+		return ActivityDetails{}, err
+	}
+
+	var pageHTML string
+	err = chromedp.Run(ctx,
+		chromedp.WaitReady("body"),
+		chromedp.OuterHTML("html", &pageHTML),
+	)
+	if err != nil {
+		return ActivityDetails{}, err
+	}
+
+	return parseActivityPage(pageHTML, pageUrl)
+}
+
+// Parses the content of an activity page. The page consists of sections with headings such as "Lektier",
+// "Øvrigt indhold" and "Note", each followed by the content of the section
+func parseActivityPage(pageHTML, pageUrl string) (ActivityDetails, error) {
+	var details ActivityDetails
+
+	doc, err := html.Parse(strings.NewReader(pageHTML))
+	if err != nil {
+		return details, err
+	}
+	base, err := url.Parse(pageUrl)
+	if err != nil {
+		return details, err
+	}
+
+	// Only the content of the activity is parsed, leaving out the navigation of Lectio
+	content := findByID(doc, "s_m_Content_Content_tocAndToolbar_inlineHomeworkDiv")
+	if content == nil {
+		content = findByID(doc, "m_Content_tocAndToolbar_inlineHomeworkDiv")
+	}
+	if content == nil {
+		content = doc
+	}
+
+	var section *string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "h1", "h2", "h3", "h4":
+				heading := strings.ToLower(strings.TrimSpace(textContent(n)))
+				switch {
+				case strings.HasPrefix(heading, "lektier"), strings.HasPrefix(heading, "homework"):
+					section = &details.Homework
+				case strings.HasPrefix(heading, "øvrigt"), strings.HasPrefix(heading, "other"):
+					section = &details.Materials
+				case strings.HasPrefix(heading, "note"), strings.HasPrefix(heading, "beskrivelse"), strings.HasPrefix(heading, "description"):
+					section = &details.Description
+				default:
+					section = nil
+				}
+				return
+			case "a":
+				if href, ok := getAttr(n, "href"); ok && isAttachmentLink(href) {
+					if link, err := base.Parse(href); err == nil {
+						name := strings.TrimSpace(textContent(n))
+						if name == "" {
+							name = link.String()
+						}
+						details.Attachments = append(details.Attachments, Attachment{Name: name, URL: link.String()})
+					}
+				}
+			case "br", "p", "div", "li":
+				if section != nil && *section != "" && !strings.HasSuffix(*section, "\n") {
+					*section += "\n"
+				}
+			case "script", "style":
+				return
+			}
+		}
+
+		if n.Type == html.TextNode && section != nil {
+			if text := strings.Join(strings.Fields(n.Data), " "); text != "" {
+				if *section != "" && !strings.HasSuffix(*section, "\n") {
+					*section += " "
+				}
+				*section += text
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(content)
+
+	details.Description = strings.TrimSpace(details.Description)
+	details.Homework = strings.TrimSpace(details.Homework)
+	details.Materials = strings.TrimSpace(details.Materials)
+	return details, nil
+}
+
+// Reports whether a link on an activity page points to an attached document or an external resource
+func isAttachmentLink(href string) bool {
+	lower := strings.ToLower(href)
+	return strings.Contains(lower, "dokumenthent.aspx") ||
+		strings.Contains(lower, "showdocument") ||
+		strings.HasPrefix(lower, "http://") ||
+		(strings.HasPrefix(lower, "https://") && !strings.Contains(lower, "lectio.dk"))
+}
+
+// Reports whether the module is an activity with a page in Lectio, as opposed to eg. a day note
+func (m *Module) hasActivityPage() bool {
+	if m.Id == "" {
+		return false
+	}
+	for _, r := range m.Id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a fingerprint of the module as shown in the schedule. The tooltip of a module changes when the teacher
+// changes its note or homework, so a changed fingerprint means the activity page must be fetched again
+func (m *Module) fingerprint() string {
+	sum := sha1.Sum([]byte(strings.Join([]string{
+		m.Title,
+		m.StartDate.String(),
+		m.EndDate.String(),
+		string(m.ModuleStatus),
+		m.Homework,
+		m.Description,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Fills the details of the activity page into the module. Details missing from the page keep the values of the schedule
+func (m *Module) applyDetails(details ActivityDetails) {
+	if details.Description != "" {
+		m.Description = details.Description + "\n"
+	}
+	if details.Homework != "" {
+		m.Homework = details.Homework + "\n"
+	}
+	m.Materials = details.Materials
+	m.Attachments = details.Attachments
+}
+
+// Finds the element with the given ID
+func findByID(n *html.Node, id string) *html.Node {
+	if value, ok := getAttr(n, "id"); ok && value == id {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findByID(c, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
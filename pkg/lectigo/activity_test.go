@@ -0,0 +1,44 @@
+package lectigo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActivityCacheGet(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	module := func(start time.Time) *Module {
+		return &Module{Id: "61234567890", Title: "Matematik", StartDate: start, EndDate: start.Add(45 * time.Minute)}
+	}
+
+	tests := []struct {
+		name      string
+		module    *Module
+		fetchedAt time.Time
+		changed   bool
+		want      bool
+	}{
+		{"past module cached long ago", module(now.AddDate(0, 0, -3)), now.AddDate(0, 0, -10), false, true},
+		{"upcoming module cached recently", module(now.AddDate(0, 0, 2)), now.Add(-time.Hour), false, true},
+		{"upcoming module cached long ago", module(now.AddDate(0, 0, 2)), now.Add(-activityRefreshAge), false, false},
+		{"ongoing module cached long ago", module(now.Add(-10 * time.Minute)), now.AddDate(0, 0, -1), false, false},
+		{"later module cached long ago", module(now.AddDate(0, 0, 14)), now.AddDate(0, 0, -1), false, true},
+		{"changed module", module(now.AddDate(0, 0, 14)), now.Add(-time.Minute), true, false},
+	}
+	for _, test := range tests {
+		fingerprint := test.module.fingerprint()
+		if test.changed {
+			fingerprint = "outdated"
+		}
+		cache := &ActivityCache{Entries: map[string]ActivityCacheEntry{
+			test.module.Id: {Fingerprint: fingerprint, FetchedAt: test.fetchedAt, Details: ActivityDetails{Homework: "Side 12"}},
+		}}
+		details, ok := cache.get(test.module.Id, test.module, now)
+		if ok != test.want {
+			t.Errorf("%s: cached = %v, want %v", test.name, ok, test.want)
+		}
+		if ok && details.Homework != "Side 12" {
+			t.Errorf("%s: details = %+v", test.name, details)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package lectigo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+// Authenticates a browser against Lectio, so that the schedule of the user can be scraped
+type Authenticator interface {
+	// Logs the browser of l in at the school of l.LoginInfo
+	Authenticate(l *Lectio) error
+}
+
+// Wraps the errors of authenticators whose credentials Lectio did not accept, so that they can be told apart from
+// Lectio being unavailable
+var ErrLoginFailed = errors.New("could not log in to Lectio")
+
+// Logs in with the username and password of the login info through the Lectio login form
+type PasswordAuthenticator struct {
+	SessionPath string // If not empty, the session is stored here and reused between runs
+}
+
+// Uses the Lectio cookies exported from a browser in which the user is logged in. This supports schools using
+// MitID or UNI-Login, where the username and password form cannot be used
+type CookieFileAuthenticator struct {
+	Path string // The path to a Netscape cookie file (cookies.txt) or a JSON cookie export
+}
+
+// Logs in to Lectio with the username and password, reusing the stored session if possible
+func (a *PasswordAuthenticator) Authenticate(l *Lectio) error {
+	ctx, loginInfo, sessionPath := l.Context, l.LoginInfo, a.SessionPath
+
+	if sessionPath != "" {
+		session, err := LoadSession(sessionPath)
+		if err == nil && session.Matches(loginInfo) && session.apply(ctx) == nil {
+			if ok, err := l.isLoggedIn(ctx); err == nil && ok {
+				l.logger().Debug("Reusing Lectio session", "path", sessionPath)
+				// Lectio refreshes the cookies on every request, so the refreshed session is stored
+				return saveSession(ctx, loginInfo, sessionPath)
+			}
+		}
+		// Fall back to logging in with the password if the session is missing or expired
+	}
+
+	loginUrl := fmt.Sprintf("https://www.lectio.dk/lectio/%s/login.aspx", loginInfo.SchoolID)
+	l.logger().Debug("Logging in to Lectio with password", "login", loginInfo)
+
+	err := l.navigate(ctx, loginUrl)
+	if err != nil {
+		return err
+	}
+
+	var autologinNodes []*cdp.Node
+	err = chromedp.Run(ctx,
+		chromedp.WaitVisible("#username"),
+		chromedp.SendKeys("#username", loginInfo.Username),
+		chromedp.SendKeys("#password", loginInfo.Password),
+		chromedp.Nodes("#m_Content_AutologinCbx", &autologinNodes, chromedp.AtLeast(0)),
+	)
+	if err != nil {
+		return err
+	}
+
+	// "Husk mig" makes Lectio issue the autologin cookie, which keeps the session valid between runs
+	if len(autologinNodes) > 0 && sessionPath != "" {
+		err = chromedp.Run(ctx, chromedp.Click("#m_Content_AutologinCbx", chromedp.NodeVisible))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Submitting the form is not retried, as Lectio might count failed attempts
+	err = l.limiter.wait(ctx)
+	if err != nil {
+		return err
+	}
+	err = chromedp.Run(ctx,
+		chromedp.Click("#m_Content_submitbtn2", chromedp.NodeVisible),
+		chromedp.WaitReady("body"),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Lectio redirects back to the login page when it rejects the password
+	ok, err := l.isLoggedIn(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w as %q", ErrLoginFailed, loginInfo.Username)
+	}
+	if sessionPath == "" {
+		return nil
+	}
+	return saveSession(ctx, loginInfo, sessionPath)
+}
+
+func saveSession(ctx context.Context, loginInfo *LectioLoginInfo, sessionPath string) error {
+	session, err := currentSession(ctx, loginInfo)
+	if err != nil {
+		return err
+	}
+	return session.Save(sessionPath)
+}
+
+// Loads the cookies of the cookie file into the browser and checks that they are logged in to Lectio
+func (a *CookieFileAuthenticator) Authenticate(l *Lectio) error {
+	ctx, loginInfo := l.Context, l.LoginInfo
+
+	cookies, err := ReadCookieFile(a.Path)
+	if err != nil {
+		return err
+	}
+
+	session := &Session{
+		SchoolID: loginInfo.SchoolID,
+		Username: loginInfo.Username,
+		SavedAt:  time.Now(),
+		Cookies:  cookies,
+	}
+	err = session.apply(ctx)
+	if err != nil {
+		return fmt.Errorf("could not load cookies from %q: %w", a.Path, err)
+	}
+
+	ok, err := l.isLoggedIn(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: the cookies in %q are not logged in. Log in to Lectio in your browser and export the cookies again", ErrLoginFailed, a.Path)
+	}
+	return nil
+}
+
+// Reads the Lectio cookies of a Netscape cookie file or a JSON cookie export. The JSON export can either be an
+// array of cookies as exported by browser extensions, or a lectigo session file
+func ReadCookieFile(path string) ([]SessionCookie, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cookies []SessionCookie
+	trimmed := strings.TrimSpace(string(b))
+	switch {
+	case strings.HasPrefix(trimmed, "["):
+		cookies, err = parseJSONCookies(b)
+	case strings.HasPrefix(trimmed, "{"):
+		session := &Session{}
+		err = json.Unmarshal(b, session)
+		cookies = session.Cookies
+	default:
+		cookies, err = parseNetscapeCookies(trimmed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse cookie file %q: %w", path, err)
+	}
+
+	var lectioCookies []SessionCookie
+	for _, cookie := range cookies {
+		if strings.HasSuffix(strings.TrimPrefix(cookie.Domain, "."), "lectio.dk") {
+			lectioCookies = append(lectioCookies, cookie)
+		}
+	}
+	if len(lectioCookies) == 0 {
+		return nil, fmt.Errorf("cookie file %q contains no Lectio cookies", path)
+	}
+	return lectioCookies, nil
+}
+
+// Parses a JSON array of cookies as exported by browser extensions (eg. Cookie-Editor)
+func parseJSONCookies(b []byte) ([]SessionCookie, error) {
+	var exported []struct {
+		Name           string  `json:"name"`
+		Value          string  `json:"value"`
+		Domain         string  `json:"domain"`
+		Path           string  `json:"path"`
+		ExpirationDate float64 `json:"expirationDate"`
+		Expires        float64 `json:"expires"`
+		HTTPOnly       bool    `json:"httpOnly"`
+		Secure         bool    `json:"secure"`
+		Session        bool    `json:"session"`
+	}
+	err := json.Unmarshal(b, &exported)
+	if err != nil {
+		return nil, err
+	}
+
+	cookies := make([]SessionCookie, len(exported))
+	for i, c := range exported {
+		cookies[i] = SessionCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+		}
+		expires := max(c.ExpirationDate, c.Expires)
+		if !c.Session && expires > 0 {
+			cookies[i].Expires = time.Unix(int64(expires), 0)
+		}
+	}
+	return cookies, nil
+}
+
+// Parses a Netscape cookie file as exported by curl, wget and browser extensions
+// Format: domain, include subdomains, path, secure, expiry, name and value separated by tabs
+func parseNetscapeCookies(content string) ([]SessionCookie, error) {
+	var cookies []SessionCookie
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+
+		httpOnly := strings.HasPrefix(text, "#HttpOnly_")
+		if httpOnly {
+			text = strings.TrimPrefix(text, "#HttpOnly_")
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d: expected 7 tab separated fields, got %d", line, len(fields))
+		}
+
+		cookie := SessionCookie{
+			Domain:   fields[0],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HTTPOnly: httpOnly,
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid expiry %q", line, fields[4])
+		}
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(cookies) == 0 {
+		return nil, errors.New("no cookies found")
+	}
+	return cookies, nil
+}
@@ -0,0 +1,147 @@
+package lectigo
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The kind of change of a module noticed by a sync
+type ChangeType string
+
+const (
+	ChangeNew       ChangeType = "new"       // The module was added to a week already in the calendar
+	ChangeCancelled ChangeType = "cancelled" // The module was cancelled
+	ChangeMoved     ChangeType = "moved"     // The start or end time of the module changed
+	ChangeRoom      ChangeType = "room"      // The room of the module changed
+	ChangeTeacher   ChangeType = "teacher"   // The teacher of the module changed
+	ChangeHomework  ChangeType = "homework"  // The module got new or changed homework
+)
+
+// All change types in the order they are reported in
+var ChangeTypes = []ChangeType{ChangeNew, ChangeCancelled, ChangeMoved, ChangeRoom, ChangeTeacher, ChangeHomework}
+
+// A change of a module noticed by a sync
+type Change struct {
+	Type     ChangeType `json:"type"`
+	Module   Module     `json:"module"`             // The module after the change
+	Previous *Module    `json:"previous,omitempty"` // The module before the change, as read back from its calendar event. Nil for new modules
+}
+
+// Parses a change type
+func ParseChangeType(s string) (ChangeType, error) {
+	for _, t := range ChangeTypes {
+		if string(t) == strings.ToLower(strings.TrimSpace(s)) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown change type %q", s)
+}
+
+// Returns a single line describing the change, eg. "Room changed: 2a MA, Mon 19 Oct 08:10 (12 -> 14)"
+func (c *Change) String() string {
+	m := &c.Module
+	when := formatModuleTime(m.StartDate, m.AllDay)
+
+	switch c.Type {
+	case ChangeNew:
+		return fmt.Sprintf("New: %s, %s", m.Title, when)
+	case ChangeCancelled:
+		return fmt.Sprintf("Cancelled: %s, %s", m.Title, when)
+	case ChangeMoved:
+		return fmt.Sprintf("Moved: %s, %s -> %s", m.Title, formatModuleTime(c.Previous.StartDate, c.Previous.AllDay), when)
+	case ChangeRoom:
+		return fmt.Sprintf("Room changed: %s, %s (%s -> %s)", m.Title, when, orNone(c.Previous.Location), orNone(m.Location))
+	case ChangeTeacher:
+		return fmt.Sprintf("Teacher changed: %s, %s (%s -> %s)", m.Title, when, orNone(c.Previous.Teacher), orNone(m.Teacher))
+	case ChangeHomework:
+		return fmt.Sprintf("Homework: %s, %s: %s", m.Title, when, m.Homework)
+	}
+	return fmt.Sprintf("%s: %s, %s", c.Type, m.Title, when)
+}
+
+func formatModuleTime(t time.Time, allDay bool) string {
+	if allDay {
+		return t.Format("Mon 2 Jan")
+	}
+	return t.Format("Mon 2 Jan 15:04")
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// Returns the changes of the module since the previous version read back from its calendar event
+func moduleChanges(m Module, previous *Module) []Change {
+	// Calendar events only keep the teacher as the first line of the description
+	if !previous.AllDay {
+		previous.Teacher, _, _ = strings.Cut(previous.Description, "\n")
+	}
+
+	var changes []Change
+	add := func(t ChangeType) {
+		changes = append(changes, Change{Type: t, Module: m, Previous: previous})
+	}
+
+	if m.ModuleStatus == StatusCancelled && previous.ModuleStatus != StatusCancelled {
+		add(ChangeCancelled)
+	}
+	if !m.StartDate.Equal(previous.StartDate) || !m.EndDate.Equal(previous.EndDate) {
+		add(ChangeMoved)
+	}
+	if m.Location != previous.Location {
+		add(ChangeRoom)
+	}
+	if !m.AllDay && m.Teacher != previous.Teacher {
+		add(ChangeTeacher)
+	}
+	if m.Homework != "" && !strings.Contains(previous.Description, "Lektier:\n"+m.Homework) {
+		add(ChangeHomework)
+	}
+	return changes
+}
+
+// Returns the weeks with events in the calendar, as "year-week". Modules inserted into these weeks are new, while
+// modules of weeks synced for the first time are not
+func syncedWeeks(events map[string]*GoogleEvent) map[string]bool {
+	weeks := make(map[string]bool)
+	for _, event := range events {
+		start := event.Start.DateTime
+		if start == "" {
+			start = event.Start.Date
+		}
+		if len(start) < len(time.DateOnly) {
+			continue
+		}
+		date, err := time.Parse(time.DateOnly, start[:len(time.DateOnly)])
+		if err != nil {
+			continue
+		}
+		weeks[weekKey(date)] = true
+	}
+	return weeks
+}
+
+func weekKey(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-%d", year, week)
+}
+
+// Sorts the changes by the start of their module, keeping the changes of a module in the order of ChangeTypes
+func sortChanges(changes []Change) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if !a.Module.StartDate.Equal(b.Module.StartDate) {
+			return a.Module.StartDate.Before(b.Module.StartDate)
+		}
+		if a.Module.Id != b.Module.Id {
+			return a.Module.Id < b.Module.Id
+		}
+		return slices.Index(ChangeTypes, a.Type) < slices.Index(ChangeTypes, b.Type)
+	})
+}
@@ -0,0 +1,76 @@
+package lectigo
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestModuleChanges(t *testing.T) {
+	start := time.Date(2026, 10, 19, 8, 15, 0, 0, time.UTC)
+	module := Module{
+		Id:           "61234567890",
+		Title:        "Matematik",
+		StartDate:    start,
+		EndDate:      start.Add(45 * time.Minute),
+		Location:     "1.23",
+		Teacher:      "ABC",
+		ModuleStatus: StatusNormal,
+	}
+	// The previous module as read back from its calendar event, which keeps the teacher in the description
+	event := module
+	event.Teacher = ""
+	event.Description = "ABC"
+
+	tests := []struct {
+		name     string
+		module   func(m *Module)
+		previous func(p *Module)
+		want     []ChangeType
+	}{
+		{"unchanged", nil, nil, nil},
+		{"cancelled", func(m *Module) { m.ModuleStatus = StatusCancelled }, nil, []ChangeType{ChangeCancelled}},
+		{"still cancelled", func(m *Module) { m.ModuleStatus = StatusCancelled }, func(p *Module) { p.ModuleStatus = StatusCancelled }, nil},
+		{"moved start", func(m *Module) { m.StartDate = start.Add(time.Hour) }, nil, []ChangeType{ChangeMoved}},
+		{"moved end", func(m *Module) { m.EndDate = start.Add(90 * time.Minute) }, nil, []ChangeType{ChangeMoved}},
+		{"room", func(m *Module) { m.Location = "2.01" }, nil, []ChangeType{ChangeRoom}},
+		{"teacher", func(m *Module) { m.Teacher = "DEF" }, nil, []ChangeType{ChangeTeacher}},
+		{"teacher of all day module", func(m *Module) { m.AllDay = true; m.Teacher = "DEF" }, func(p *Module) { p.AllDay = true }, nil},
+		{"new homework", func(m *Module) { m.Homework = "Side 12-14" }, nil, []ChangeType{ChangeHomework}},
+		{"same homework", func(m *Module) { m.Homework = "Side 12-14" }, func(p *Module) { p.Description = "ABC\n\nLektier:\nSide 12-14" }, nil},
+		{"changed homework", func(m *Module) { m.Homework = "Side 15" }, func(p *Module) { p.Description = "ABC\n\nLektier:\nSide 12-14" }, []ChangeType{ChangeHomework}},
+		{
+			"cancelled and moved to another room",
+			func(m *Module) {
+				m.ModuleStatus = StatusCancelled
+				m.StartDate = start.Add(time.Hour)
+				m.Location = "2.01"
+			},
+			nil,
+			[]ChangeType{ChangeCancelled, ChangeMoved, ChangeRoom},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, previous := module, event
+			if test.module != nil {
+				test.module(&m)
+			}
+			if test.previous != nil {
+				test.previous(&previous)
+			}
+
+			changes := moduleChanges(m, &previous)
+			var got []ChangeType
+			for _, change := range changes {
+				got = append(got, change.Type)
+				if change.Previous != &previous || change.Module.Id != m.Id {
+					t.Errorf("change %s does not refer to the module and its previous version", change.Type)
+				}
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("changes = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
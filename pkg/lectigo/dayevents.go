@@ -0,0 +1,165 @@
+package lectigo
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Keywords marking an all-day item as a holiday or a day without teaching
+var holidayKeywords = []string{
+	"ferie",
+	"helligdag",
+	"fridag",
+	"ingen undervisning",
+	"skolen er lukket",
+	"holiday",
+	"vacation",
+	"no classes",
+	"no teaching",
+}
+
+var reDayHeader = regexp.MustCompile(`\((\d{1,2})/(\d{1,2})\)`) // eg. "Mandag (4/3)" or "Monday (4/3)"
+
+// Reports whether the title of an all-day item marks a holiday
+func isHolidayTitle(title string) bool {
+	title = strings.ToLower(title)
+	for _, keyword := range holidayKeywords {
+		if strings.Contains(title, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// Creates an all-day module from a day note in the info header of the schedule
+func newDayNote(note string, date time.Time) Module {
+	lines := strings.Split(note, "\n")
+	return Module{
+		Id:          dayNoteID(date, note),
+		Title:       lines[0],
+		StartDate:   date,
+		EndDate:     date.AddDate(0, 0, 1),
+		Description: strings.Join(lines[1:], "\n"),
+		AllDay:      true,
+		Holiday:     isHolidayTitle(note),
+	}
+}
+
+// Returns a stable ID for all-day items that have no activity ID in Lectio.
+// Google Calendar IDs only allow the characters a-v and 0-9, so the ID is the date and a hex digest of the text
+func dayNoteID(date time.Time, text string) string {
+	sum := sha1.Sum([]byte(text))
+	return "d" + date.Format("20060102") + hex.EncodeToString(sum[:8])
+}
+
+// Returns the dates of the columns in the day header of the schedule, indexed by column.
+// Columns without a date (eg. the time column) are zero
+func parseDayHeaders(doc *html.Node, year int) []time.Time {
+	var dates []time.Time
+	location, _ := time.LoadLocation("Europe/Copenhagen")
+
+	var find func(n *html.Node)
+	find = func(n *html.Node) {
+		if dates != nil {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "tr" && (hasClass(n, "s2dayHeader") || hasChildWithClass(n, "s2dayHeader")) {
+			dates = []time.Time{}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type != html.ElementNode || c.Data != "td" {
+					continue
+				}
+				var date time.Time
+				if matches := reDayHeader.FindStringSubmatch(textContent(c)); matches != nil {
+					day, _ := strconv.Atoi(matches[1])
+					month, _ := strconv.Atoi(matches[2])
+					date = time.Date(year, time.Month(month), day, 0, 0, 0, 0, location)
+				}
+				dates = append(dates, date)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+	return dates
+}
+
+// Returns the text of a day note cell, leaving out the text of schedule bricks within it
+func dayNoteText(n *html.Node) string {
+	var lines []string
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.ElementNode && hasClass(n, "s2skemabrik") {
+			return
+		}
+		if n.Type == html.TextNode {
+			if text := strings.TrimSpace(n.Data); text != "" {
+				lines = append(lines, text)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return strings.Join(lines, "\n")
+}
+
+// Returns the index of a table cell within its row
+func columnIndex(n *html.Node) int {
+	index := 0
+	for c := n.PrevSibling; c != nil; c = c.PrevSibling {
+		if c.Type == html.ElementNode && c.Data == "td" {
+			index++
+		}
+	}
+	return index
+}
+
+func getAttr(n *html.Node, key string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+func hasClass(n *html.Node, class string) bool {
+	classes, _ := getAttr(n, "class")
+	for _, c := range strings.Fields(classes) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func hasChildWithClass(n *html.Node, class string) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && hasClass(c, class) {
+			return true
+		}
+	}
+	return false
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(textContent(c))
+	}
+	return sb.String()
+}
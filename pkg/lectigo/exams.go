@@ -0,0 +1,301 @@
+package lectigo
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"golang.org/x/net/html"
+)
+
+const colorIDExam = "11" // Red (tomato)
+
+var (
+	reExamDate    = regexp.MustCompile(`(\d{1,2})/(\d{1,2})-(\d{4})`)
+	reExamTime    = regexp.MustCompile(`(\d{1,2})[:.](\d{2})`)
+	reStudentID   = regexp.MustCompile(`elevid=(\d+)`)
+	reMinutesOnly = regexp.MustCompile(`^(\d+)\s*(?:min|minutter|minutes)\.?$`)
+)
+
+// An exam of the student, as shown on the exam overview ("Prøver") in Lectio
+type Exam struct {
+	Id               string    `json:"id"`               // Stable ID derived from the team, subject, form and start of the exam
+	Subject          string    `json:"subject"`          // The subject of the exam (eg. Matematik A)
+	Team             string    `json:"team"`             // The team of the exam (eg. 3a MA)
+	Form             string    `json:"form"`             // The form of the exam (eg. Mundtlig or Skriftlig)
+	StartDate        time.Time `json:"startDate"`        // The start of the examination
+	EndDate          time.Time `json:"endDate"`          // The end of the examination
+	PreparationStart time.Time `json:"preparationStart"` // The start of the preparation. Zero if the exam has no preparation
+	Room             string    `json:"room"`             // The room of the exam
+	Censor           string    `json:"censor"`           // The censor of the exam
+}
+
+// Gets the exams of the logged in student
+func (l *Lectio) GetExams() ([]Exam, error) {
+	studentID, err := l.studentID(l.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	pageUrl := fmt.Sprintf("https://www.lectio.dk/lectio/%s/proevehold.aspx?type=elev&elevid=%s", l.LoginInfo.SchoolID, studentID)
+	err = l.navigate(l.Context, pageUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	var pageHTML string
+	err = chromedp.Run(l.Context,
+		chromedp.WaitReady("body"),
+		chromedp.OuterHTML("html", &pageHTML),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseExams(pageHTML)
+}
+
+// Finds the Lectio ID of the logged in student from the links on the front page
+func (l *Lectio) studentID(ctx context.Context) (string, error) {
+	err := l.navigate(ctx, fmt.Sprintf("https://www.lectio.dk/lectio/%s/forside.aspx", l.LoginInfo.SchoolID))
+	if err != nil {
+		return "", err
+	}
+
+	var pageHTML string
+	err = chromedp.Run(ctx, chromedp.OuterHTML("html", &pageHTML))
+	if err != nil {
+		return "", err
+	}
+
+	matches := reStudentID.FindStringSubmatch(pageHTML)
+	if matches == nil {
+		return "", errors.New("could not find the student ID of the logged in user")
+	}
+	return matches[1], nil
+}
+
+// Parses the exams of the exam overview. The overview is a table with a header row, whose columns are found by
+// their Danish or English headings
+func parseExams(pageHTML string) ([]Exam, error) {
+	doc, err := html.Parse(strings.NewReader(pageHTML))
+	if err != nil {
+		return nil, err
+	}
+
+	var exams []Exam
+	var findTables func(n *html.Node)
+	findTables = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "table" {
+			exams = append(exams, parseExamTable(n)...)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			findTables(c)
+		}
+	}
+	findTables(doc)
+	return exams, nil
+}
+
+// Parses the rows of a table of exams. Tables without a date column are not exam tables and result in no exams
+func parseExamTable(table *html.Node) []Exam {
+	var rows [][]string
+	var findRows func(n *html.Node)
+	findRows = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			var cells []string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.Data == "td" || c.Data == "th") {
+					cells = append(cells, strings.Join(strings.Fields(textContent(c)), " "))
+				}
+			}
+			rows = append(rows, cells)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			// Nested tables are parsed on their own
+			if c.Type == html.ElementNode && c.Data == "table" {
+				continue
+			}
+			findRows(c)
+		}
+	}
+	findRows(table)
+	if len(rows) < 2 {
+		return nil
+	}
+
+	columns := examColumns(rows[0])
+	if _, ok := columns["date"]; !ok {
+		return nil
+	}
+
+	var exams []Exam
+	for _, row := range rows[1:] {
+		cell := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+
+		exam, ok := parseExamRow(cell)
+		if ok {
+			exams = append(exams, exam)
+		}
+	}
+	return exams
+}
+
+// Maps the headings of an exam table to the index of their column
+func examColumns(headings []string) map[string]int {
+	keywords := []struct {
+		column   string
+		keywords []string
+	}{
+		{"preparation", []string{"forberedelse", "preparation"}},
+		{"date", []string{"dato", "date"}},
+		{"time", []string{"tid", "time", "eksamination", "examination"}},
+		{"team", []string{"hold", "team"}},
+		{"subject", []string{"fag", "subject"}},
+		{"form", []string{"prøveform", "form", "type"}},
+		{"room", []string{"lokale", "room"}},
+		{"censor", []string{"censor"}},
+	}
+
+	columns := make(map[string]int)
+	for i, heading := range headings {
+		heading = strings.ToLower(heading)
+		for _, k := range keywords {
+			if _, ok := columns[k.column]; ok {
+				continue
+			}
+			if hasLabel(heading, k.keywords) {
+				columns[k.column] = i
+				break
+			}
+		}
+	}
+	return columns
+}
+
+// Parses a row of an exam table. The boolean reports whether the row contained a dated exam
+func parseExamRow(cell func(column string) string) (Exam, bool) {
+	location, _ := time.LoadLocation("Europe/Copenhagen")
+
+	dateText := cell("date")
+	dateMatches := reExamDate.FindStringSubmatch(dateText)
+	if dateMatches == nil {
+		return Exam{}, false
+	}
+	year, _ := strconv.Atoi(dateMatches[3])
+	month, _ := strconv.Atoi(dateMatches[2])
+	day, _ := strconv.Atoi(dateMatches[1])
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, location)
+
+	// The time is either in its own column or after the date
+	times := parseClockTimes(date, cell("time"))
+	if len(times) == 0 {
+		times = parseClockTimes(date, strings.TrimPrefix(dateText, dateMatches[0]))
+	}
+
+	exam := Exam{
+		Subject: cell("subject"),
+		Team:    cell("team"),
+		Form:    cell("form"),
+		Room:    cell("room"),
+		Censor:  cell("censor"),
+	}
+
+	switch len(times) {
+	case 0:
+		// Exams without a time (eg. written exams before the plan is out) last all day
+		exam.StartDate = date
+		exam.EndDate = date.AddDate(0, 0, 1)
+	case 1:
+		exam.StartDate = times[0]
+		exam.EndDate = times[0].Add(time.Hour)
+	default:
+		exam.StartDate = times[0]
+		exam.EndDate = times[1]
+	}
+
+	preparation := cell("preparation")
+	if preparationTimes := parseClockTimes(date, preparation); len(preparationTimes) > 0 {
+		exam.PreparationStart = preparationTimes[0]
+	} else if matches := reMinutesOnly.FindStringSubmatch(strings.ToLower(preparation)); matches != nil && len(times) > 0 {
+		minutes, _ := strconv.Atoi(matches[1])
+		exam.PreparationStart = exam.StartDate.Add(-time.Duration(minutes) * time.Minute)
+	}
+
+	// The form and the start tell apart exams of the same team on the same day, eg. a written and an oral exam
+	sum := sha1.Sum([]byte(exam.Team + "\x00" + exam.Subject + "\x00" + exam.Form + "\x00" + exam.StartDate.Format("2006-01-02 15:04")))
+	exam.Id = "e" + hex.EncodeToString(sum[:8])
+	return exam, true
+}
+
+// Parses the clock times (eg. "09:00 - 09:30") of a text on the given date
+func parseClockTimes(date time.Time, text string) []time.Time {
+	var times []time.Time
+	for _, matches := range reExamTime.FindAllStringSubmatch(text, -1) {
+		hour, _ := strconv.Atoi(matches[1])
+		minute, _ := strconv.Atoi(matches[2])
+		times = append(times, time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, date.Location()))
+	}
+	return times
+}
+
+// Converts the exam to a highlighted module. The module starts at the preparation if the exam has one, and has the
+// given reminders in minutes before its start
+func (e *Exam) ToModule(reminders []int) Module {
+	title := "Eksamen: " + e.Subject
+	if e.Subject == "" {
+		title = "Eksamen: " + e.Team
+	}
+	if e.Form != "" {
+		title += fmt.Sprintf(" (%s)", e.Form)
+	}
+
+	var description []string
+	if !e.PreparationStart.IsZero() {
+		description = append(description, fmt.Sprintf("Forberedelse: %s", e.PreparationStart.Format("15:04")))
+	}
+	// Days are 23 or 25 hours long when daylight saving time starts or ends, so the dates are compared instead
+	allDay := e.EndDate.Equal(e.StartDate.AddDate(0, 0, 1)) && e.StartDate.Hour() == 0 && e.StartDate.Minute() == 0
+	if !allDay {
+		description = append(description, fmt.Sprintf("Eksamination: %s - %s", e.StartDate.Format("15:04"), e.EndDate.Format("15:04")))
+	}
+	if e.Team != "" {
+		description = append(description, "Hold: "+e.Team)
+	}
+	if e.Censor != "" {
+		description = append(description, "Censor: "+e.Censor)
+	}
+
+	module := Module{
+		Id:          e.Id,
+		Title:       title,
+		StartDate:   e.StartDate,
+		EndDate:     e.EndDate,
+		Description: strings.Join(description, "\n") + "\n",
+		AllDay:      allDay,
+		Exam:        true,
+		Reminders:   reminders,
+	}
+	if !e.PreparationStart.IsZero() && e.PreparationStart.Before(e.StartDate) {
+		module.StartDate = e.PreparationStart
+	}
+	if e.Room != "" {
+		module.Rooms = splitList("Lokale: " + e.Room)
+		module.Location = formatRooms(module.Rooms)
+	}
+	return module
+}
@@ -0,0 +1,56 @@
+package lectigo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExamToModuleAllDay(t *testing.T) {
+	copenhagen, err := time.LoadLocation("Europe/Copenhagen")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+
+	tests := []struct {
+		name  string
+		start time.Time
+		end   time.Time
+		want  bool
+	}{
+		{"normal day", time.Date(2026, 6, 1, 0, 0, 0, 0, copenhagen), time.Date(2026, 6, 2, 0, 0, 0, 0, copenhagen), true},
+		{"daylight saving time starts", time.Date(2026, 3, 29, 0, 0, 0, 0, copenhagen), time.Date(2026, 3, 30, 0, 0, 0, 0, copenhagen), true},
+		{"daylight saving time ends", time.Date(2026, 10, 25, 0, 0, 0, 0, copenhagen), time.Date(2026, 10, 26, 0, 0, 0, 0, copenhagen), true},
+		{"timed", time.Date(2026, 6, 1, 9, 0, 0, 0, copenhagen), time.Date(2026, 6, 1, 13, 0, 0, 0, copenhagen), false},
+		{"two days", time.Date(2026, 6, 1, 0, 0, 0, 0, copenhagen), time.Date(2026, 6, 3, 0, 0, 0, 0, copenhagen), false},
+	}
+	for _, test := range tests {
+		exam := Exam{Id: "exam", Subject: "Matematik A", StartDate: test.start, EndDate: test.end}
+		if got := exam.ToModule(nil).AllDay; got != test.want {
+			t.Errorf("%s: all day = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseExamRowIDs(t *testing.T) {
+	row := func(values map[string]string) func(column string) string {
+		return func(column string) string { return values[column] }
+	}
+	parse := func(values map[string]string) Exam {
+		exam, ok := parseExamRow(row(values))
+		if !ok {
+			t.Fatalf("could not parse exam %v", values)
+		}
+		return exam
+	}
+
+	written := parse(map[string]string{"date": "2/6-2026", "time": "09:00 - 14:00", "team": "3a MA", "subject": "Matematik A", "form": "Skriftlig"})
+	oral := parse(map[string]string{"date": "2/6-2026", "time": "15:00 - 15:30", "team": "3a MA", "subject": "Matematik A", "form": "Mundtlig"})
+	if written.Id == oral.Id {
+		t.Errorf("a written and an oral exam on the same day have the same ID %q", written.Id)
+	}
+
+	again := parse(map[string]string{"date": "2/6-2026", "time": "09:00 - 14:00", "team": "3a MA", "subject": "Matematik A", "form": "Skriftlig", "room": "1.23"})
+	if again.Id != written.Id {
+		t.Errorf("the ID changed from %q to %q with the room", written.Id, again.Id)
+	}
+}
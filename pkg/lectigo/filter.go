@@ -0,0 +1,391 @@
+package lectigo
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Whether a filter rule keeps or drops the modules it matches
+type FilterAction string
+
+const (
+	FilterInclude FilterAction = "include"
+	FilterExclude FilterAction = "exclude"
+)
+
+// A list of rules deciding which modules are kept. Every module is decided by the first rule matching it, and
+// modules matching no rule are kept
+type Filter struct {
+	Rules []FilterRule
+}
+
+// A rule of a filter, excluding or including the modules matching its conditions
+type FilterRule struct {
+	Name       string       `yaml:"name"`   // Identifies the rule in the output of the filters test command
+	Action     FilterAction `yaml:"action"` // Defaults to exclude
+	Conditions `yaml:",inline"`
+}
+
+// Conditions selecting modules. A module matches the conditions when it matches every condition given, and a
+// condition with several values when it matches any of them. Without conditions, every module matches.
+//
+// Title, team, teacher and room are matched by patterns: "text" matches values containing the text ignoring case,
+// "=text" matches values equal to the text and "/expression/" matches values matching the regular expression
+type Conditions struct {
+	Title   StringList `yaml:"title"`   // The title of the module, both as shown in Lectio and as decoded
+	Team    StringList `yaml:"team"`    // The teams of the module (eg. "3a MA")
+	Teacher StringList `yaml:"teacher"` // The initials or names of the teachers of the module
+	Room    StringList `yaml:"room"`    // The rooms of the module
+	Status  StringList `yaml:"status"`  // normal, changed or cancelled
+	Weekday StringList `yaml:"weekday"` // Weekdays in English or Danish, eg. monday, mon or mandag
+	Time    string     `yaml:"time"`    // The start time of the module, eg. "08:00-10:00" or "15:10-". Never matches all-day modules
+	Date    string     `yaml:"date"`    // The date of the module, eg. "2026-10-19", "2026-10-19..2026-10-23" or "2026-12-01.."
+
+	title, team, teacher, room []pattern
+	statuses                   []ModuleStatus
+	weekdays                   []time.Weekday
+	timeFrom, timeTo           time.Duration // Time of day. Zero timeTo for no upper bound
+	hasTime                    bool
+	dateFrom, dateTo           string // Dates as YYYY-MM-DD. Empty for no bound
+}
+
+// A list of strings, given in YAML as either a list or a single string
+type StringList []string
+
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Tag == "!!null" {
+		*l = nil
+		return nil
+	}
+	if value.Kind == yaml.ScalarNode {
+		*l = StringList{value.Value}
+		return nil
+	}
+	var list []string
+	err := value.Decode(&list)
+	if err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Parses a YAML list of filter rules
+func ParseFilter(b []byte) (*Filter, error) {
+	filter := &Filter{}
+	err := yaml.Unmarshal(b, &filter.Rules)
+	if err != nil {
+		return nil, err
+	}
+	for i := range filter.Rules {
+		err = filter.Rules[i].compile()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filter.Rules[i].DisplayName(i), err)
+		}
+	}
+	return filter, nil
+}
+
+// Reads a YAML list of filter rules from a file
+func LoadFilter(path string) (*Filter, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := ParseFilter(b)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse filters file %q: %w", path, err)
+	}
+	return filter, nil
+}
+
+// Returns the index of the first rule matching the module, or -1 if no rule matches. The title is the raw,
+// undecoded title of the module, or empty if unknown
+func (f *Filter) Match(m *Module, title string) int {
+	if f == nil {
+		return -1
+	}
+	for i := range f.Rules {
+		if f.Rules[i].Matches(m, title) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Reports whether the filter keeps the module
+func (f *Filter) Includes(m *Module, title string) bool {
+	i := f.Match(m, title)
+	return i < 0 || f.Rules[i].Action == FilterInclude
+}
+
+// Returns the name of the rule, or a name from its position in the filter if it has none
+func (r *FilterRule) DisplayName(i int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("rule %v", i+1)
+}
+
+// Reports whether the module matches every condition. The title is the raw, undecoded title of the module, or empty
+// if unknown
+func (r *Conditions) Matches(m *Module, title string) bool {
+	if len(r.title) > 0 && !matchAny(r.title, m.Title, title) {
+		return false
+	}
+	if len(r.team) > 0 && !matchAny(r.team, m.Teams...) {
+		return false
+	}
+	if len(r.teacher) > 0 {
+		teachers := []string{m.Teacher}
+		for _, teacher := range m.Teachers {
+			teachers = append(teachers, teacher.Initials, teacher.Name)
+		}
+		if !matchAny(r.teacher, teachers...) {
+			return false
+		}
+	}
+	if len(r.room) > 0 && !matchAny(r.room, append([]string{m.Location}, m.Rooms...)...) {
+		return false
+	}
+	if len(r.statuses) > 0 && !slices.Contains(r.statuses, m.ModuleStatus) {
+		return false
+	}
+	if len(r.weekdays) > 0 && !slices.Contains(r.weekdays, m.StartDate.Weekday()) {
+		return false
+	}
+	if r.hasTime {
+		if m.AllDay {
+			return false
+		}
+		start := time.Duration(m.StartDate.Hour())*time.Hour + time.Duration(m.StartDate.Minute())*time.Minute
+		if start < r.timeFrom || (r.timeTo != 0 && start >= r.timeTo) {
+			return false
+		}
+	}
+	date := m.StartDate.Format(time.DateOnly)
+	if (r.dateFrom != "" && date < r.dateFrom) || (r.dateTo != "" && date > r.dateTo) {
+		return false
+	}
+	return true
+}
+
+// Checks the action of the rule and parses its conditions
+func (r *FilterRule) compile() error {
+	switch r.Action {
+	case "":
+		r.Action = FilterExclude
+	case FilterInclude, FilterExclude:
+	default:
+		return fmt.Errorf("unknown action %q (use include or exclude)", r.Action)
+	}
+	return r.Conditions.compile()
+}
+
+// Parses the conditions
+func (r *Conditions) compile() error {
+	var err error
+	for _, field := range []struct {
+		patterns []string
+		compiled *[]pattern
+	}{{r.Title, &r.title}, {r.Team, &r.team}, {r.Teacher, &r.teacher}, {r.Room, &r.room}} {
+		*field.compiled = nil
+		for _, s := range field.patterns {
+			p, err := parsePattern(s)
+			if err != nil {
+				return err
+			}
+			*field.compiled = append(*field.compiled, p)
+		}
+	}
+
+	r.statuses = nil
+	for _, s := range r.Status {
+		status, ok := ParseModuleStatus(s)
+		if !ok && !strings.EqualFold(strings.TrimSpace(s), "normal") {
+			return fmt.Errorf("unknown status %q (use normal, changed or cancelled)", s)
+		}
+		r.statuses = append(r.statuses, status)
+	}
+
+	r.weekdays = nil
+	for _, s := range r.Weekday {
+		weekday, err := parseWeekday(s)
+		if err != nil {
+			return err
+		}
+		r.weekdays = append(r.weekdays, weekday)
+	}
+
+	r.hasTime = r.Time != ""
+	if r.hasTime {
+		r.timeFrom, r.timeTo, err = parseTimeRange(r.Time)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.dateFrom, r.dateTo, err = parseDateRange(r.Date)
+	return err
+}
+
+// A compiled pattern of a condition
+type pattern struct {
+	text  string // Lowercased text contained in matching values, if the pattern is neither exact nor a regular expression
+	exact string
+	re    *regexp.Regexp
+}
+
+func parsePattern(s string) (pattern, error) {
+	switch {
+	case len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/"):
+		re, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return pattern{}, fmt.Errorf("invalid regular expression %q: %w", s, err)
+		}
+		return pattern{re: re}, nil
+	case strings.HasPrefix(s, "="):
+		// An empty exact pattern would be taken for the empty text, which every value contains
+		if s == "=" {
+			return pattern{}, fmt.Errorf("empty exact match %q", s)
+		}
+		return pattern{exact: s[1:]}, nil
+	}
+	return pattern{text: strings.ToLower(s)}, nil
+}
+
+func (p pattern) match(value string) bool {
+	switch {
+	case p.re != nil:
+		return p.re.MatchString(value)
+	case p.exact != "":
+		return value == p.exact
+	}
+	return strings.Contains(strings.ToLower(value), p.text)
+}
+
+// Reports whether any of the non-empty values matches any of the patterns
+func matchAny(patterns []pattern, values ...string) bool {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		for _, p := range patterns {
+			if p.match(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// The names of the weekdays in English and Danish
+var weekdayNames = map[string]time.Weekday{
+	"monday": time.Monday, "mon": time.Monday, "mandag": time.Monday, "man": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tirsdag": time.Tuesday, "tir": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday, "onsdag": time.Wednesday, "ons": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "torsdag": time.Thursday, "tor": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday, "fredag": time.Friday, "fre": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday, "lørdag": time.Saturday, "lør": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday, "søndag": time.Sunday, "søn": time.Sunday,
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	weekday, ok := weekdayNames[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, fmt.Errorf("unknown weekday %q", s)
+	}
+	return weekday, nil
+}
+
+// Parses a range of times of day like "08:00-10:00", "15:10-" or "-12:00". A missing end is returned as zero
+func parseTimeRange(s string) (from, to time.Duration, err error) {
+	fromValue, toValue, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid time range %q (use eg. 08:00-10:00 or 15:10-)", s)
+	}
+	if fromValue = strings.TrimSpace(fromValue); fromValue != "" {
+		from, err = parseTimeOfDay(fromValue)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if toValue = strings.TrimSpace(toValue); toValue != "" {
+		to, err = parseTimeOfDay(toValue)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return from, to, nil
+}
+
+// Parses a time of day like "15:10" or "1510"
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		t, err = time.Parse("1504", s)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Parses a range of dates like "2026-10-19..2026-10-23" or "2026-12-01..", or a single date
+func parseDateRange(s string) (from, to string, err error) {
+	if s == "" {
+		return "", "", nil
+	}
+	from, to, ok := strings.Cut(s, "..")
+	if !ok {
+		to = from
+	}
+	for _, date := range []*string{&from, &to} {
+		*date = strings.TrimSpace(*date)
+		if *date == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, *date); err != nil {
+			return "", "", fmt.Errorf("invalid date %q (use YYYY-MM-DD)", *date)
+		}
+	}
+	return from, to, nil
+}
+
+// Returns the classes to ignore as a filter rule excluding them. All-day modules are never excluded
+func (c ClassesToIgnore) Rule() FilterRule {
+	rule := FilterRule{Name: "blacklist", Action: FilterExclude, Conditions: Conditions{Time: "00:00-"}}
+	if c.Time != "" {
+		rule.Name = fmt.Sprintf("blacklist after %s", c.Time)
+		rule.Time = c.Time + "-"
+	}
+	rule.Title = append(rule.Title, c.Keywords...)
+	for _, exactMatch := range c.ExactMatches {
+		rule.Title = append(rule.Title, "="+exactMatch)
+	}
+	return rule
+}
+
+// Returns the blacklist as filter rules. Classes without keywords and exact matches ignore nothing, so they are left
+// out rather than given a rule matching every module
+func BlacklistRules(blacklist []ClassesToIgnore) ([]FilterRule, error) {
+	var rules []FilterRule
+	for _, classes := range blacklist {
+		if len(classes.Keywords) == 0 && len(classes.ExactMatches) == 0 {
+			continue
+		}
+		rule := classes.Rule()
+		err := rule.compile()
+		if err != nil {
+			return nil, fmt.Errorf("blacklist: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,208 @@
+package lectigo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Monday 19 October 2026 at 08:15
+var filterStart = time.Date(2026, 10, 19, 8, 15, 0, 0, time.UTC)
+
+func filterModule() Module {
+	return Module{
+		Id:           "1",
+		Title:        "Biologi",
+		StartDate:    filterStart,
+		EndDate:      filterStart.Add(45 * time.Minute),
+		Teams:        []string{"3a BI"},
+		Teacher:      "ABC",
+		Teachers:     []Teacher{{Initials: "ABC", Name: "Anders Bent Christensen"}},
+		Location:     "1.23",
+		ModuleStatus: StatusNormal,
+	}
+}
+
+func TestConditionsMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   string
+		module func(m *Module)
+		title  string
+		want   bool
+	}{
+		{"no conditions", "{}", nil, "", true},
+		{"title contains ignoring case", "title: BIO", nil, "", true},
+		{"title not contained", "title: fysik", nil, "", false},
+		{"raw title", "title: 3a BI", nil, "3a BI", true},
+		{"exact title", "title: =Biologi", nil, "", true},
+		{"exact title differing in case", "title: =biologi", nil, "", false},
+		{"regular expression", "title: /^bi(ologi)?$/", func(m *Module) { m.Title = "bi" }, "", true},
+		{"regular expression not matching", "title: /^fy/", nil, "", false},
+		{"any of several titles", "title: [fysik, biologi]", nil, "", true},
+		{"team", "team: =3a BI", nil, "", true},
+		{"teacher initials", "teacher: abc", nil, "", true},
+		{"teacher name", "teacher: bent", nil, "", true},
+		{"other teacher", "teacher: =DEF", nil, "", false},
+		{"room", "room: 1.2", nil, "", true},
+		{"one of the rooms", "room: =2.01", func(m *Module) { m.Rooms = []string{"1.23", "2.01"} }, "", true},
+		{"status", "status: cancelled", func(m *Module) { m.ModuleStatus = StatusCancelled }, "", true},
+		{"normal status", "status: normal", nil, "", true},
+		{"other status", "status: changed", nil, "", false},
+		{"weekday in English", "weekday: monday", nil, "", true},
+		{"weekday in Danish", "weekday: [tir, ons, man]", nil, "", true},
+		{"other weekday", "weekday: friday", nil, "", false},
+		{"time within", `time: "08:00-10:00"`, nil, "", true},
+		{"time at end", `time: "07:00-08:15"`, nil, "", false},
+		{"time without end", `time: "08:15-"`, nil, "", true},
+		{"time without start", `time: "-0815"`, nil, "", false},
+		{"time of all-day module", `time: "00:00-"`, func(m *Module) { m.AllDay = true }, "", false},
+		{"date", "date: 2026-10-19", nil, "", true},
+		{"date range", "date: 2026-10-12..2026-10-19", nil, "", true},
+		{"open date range", "date: 2026-10-20..", nil, "", false},
+		{"every condition", "{title: bio, weekday: monday, time: \"08:00-\"}", nil, "", true},
+		{"not every condition", "{title: bio, weekday: tuesday}", nil, "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := ParseFilter([]byte("- " + test.rule))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := filterModule()
+			if test.module != nil {
+				test.module(&m)
+			}
+			if got := filter.Rules[0].Matches(&m, test.title); got != test.want {
+				t.Errorf("%s matches = %v, want %v", test.rule, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterInvalid(t *testing.T) {
+	for _, rule := range []string{
+		"action: drop",
+		"title: /[/",
+		`title: "="`,
+		"team: [3a BI, =]",
+		"status: postponed",
+		"weekday: someday",
+		`time: "08:00"`,
+		`time: "8-10"`,
+		"date: 19-10-2026",
+	} {
+		_, err := ParseFilter([]byte("- " + rule))
+		if err == nil {
+			t.Errorf("ParseFilter(%q) succeeded", rule)
+		}
+	}
+}
+
+func TestFilterFirstMatchWins(t *testing.T) {
+	filter, err := ParseFilter([]byte(`
+- name: keep my biology
+  action: include
+  teacher: ABC
+- name: no biology
+  title: biologi
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.Rules[1].Action != FilterExclude {
+		t.Errorf("action = %q, want exclude by default", filter.Rules[1].Action)
+	}
+
+	mine := filterModule()
+	other := filterModule()
+	other.Teacher, other.Teachers = "DEF", nil
+	fysik := filterModule()
+	fysik.Title, fysik.Teacher, fysik.Teachers = "Fysik", "DEF", nil
+
+	for _, test := range []struct {
+		module Module
+		match  int
+		keep   bool
+	}{{mine, 0, true}, {other, 1, false}, {fysik, -1, true}} {
+		if got := filter.Match(&test.module, ""); got != test.match {
+			t.Errorf("%s of %s matches rule %v, want %v", test.module.Title, test.module.Teacher, got, test.match)
+		}
+		if got := filter.Includes(&test.module, ""); got != test.keep {
+			t.Errorf("%s of %s is kept = %v, want %v", test.module.Title, test.module.Teacher, got, test.keep)
+		}
+	}
+
+	var none *Filter
+	if !none.Includes(&mine, "") {
+		t.Error("a nil filter excluded a module")
+	}
+}
+
+// The blacklist check before it was converted to filter rules
+func oldIsClassBlacklisted(blacklist []ClassesToIgnore, title string, startDate time.Time) bool {
+	for _, ignorePastTime := range blacklist {
+		ignoreTime, _ := time.Parse("1504", ignorePastTime.Time)
+		moduleTime := time.Date(0, 1, 1, startDate.Hour(), startDate.Minute(), 0, 0, time.UTC)
+
+		if moduleTime.Compare(ignoreTime) >= 0 {
+			for _, keyword := range ignorePastTime.Keywords {
+				if strings.Contains(strings.ToLower(title), keyword) {
+					return true
+				}
+			}
+			for _, exactMatch := range ignorePastTime.ExactMatches {
+				if title == exactMatch {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func TestBlacklistRulesMatchOldBlacklist(t *testing.T) {
+	blacklist := []ClassesToIgnore{
+		{Time: "0000", Keywords: []string{"studiecafé"}},
+		{Time: "1500", Keywords: []string{"lektiecafé", "fredagsbar"}, ExactMatches: []string{"Møde"}},
+		{Time: "1200", ExactMatches: []string{"Frokost"}},
+	}
+	rules, err := BlacklistRules(blacklist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &Lectio{Blacklist: rules}
+
+	for _, title := range []string{"Studiecafé", "Lektiecafé", "Fredagsbar i kantinen", "Møde", "møde", "Frokost", "Matematik"} {
+		for _, clock := range []string{"08:00", "12:00", "14:59", "15:00", "16:30"} {
+			at, _ := time.Parse("15:04", clock)
+			start := time.Date(2026, 10, 19, at.Hour(), at.Minute(), 0, 0, time.UTC)
+			m := Module{Title: title, StartDate: start, EndDate: start.Add(time.Hour)}
+
+			want := oldIsClassBlacklisted(blacklist, title, start)
+			if got := l.isClassBlacklisted(&m, title); got != want {
+				t.Errorf("%q at %s: blacklisted = %v, want %v as before", title, clock, got, want)
+			}
+		}
+	}
+}
+
+func TestClassesToIgnoreRule(t *testing.T) {
+	rule := ClassesToIgnore{Time: "1500", Keywords: []string{"café"}, ExactMatches: []string{"Møde"}}.Rule()
+	if rule.Name != "blacklist after 1500" || rule.Action != FilterExclude || rule.Time != "1500-" {
+		t.Errorf("rule = %+v", rule)
+	}
+	if got := fmt.Sprint(rule.Title); got != "[café =Møde]" {
+		t.Errorf("title = %s, want [café =Møde]", got)
+	}
+
+	rules, err := BlacklistRules([]ClassesToIgnore{{Time: "1500"}})
+	if err != nil || len(rules) != 0 {
+		t.Errorf("a blacklist entry without keywords gave the rules %+v (%v), want none", rules, err)
+	}
+	_, err = BlacklistRules([]ClassesToIgnore{{Time: "25:00", Keywords: []string{"café"}}})
+	if err == nil {
+		t.Error("a blacklist entry with an invalid time was accepted")
+	}
+}
@@ -2,12 +2,15 @@ package lectigo
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mattismoel/lectigo/util"
@@ -17,9 +20,38 @@ import (
 
 // Base struct for a Google Calendar client
 type GoogleCalendar struct {
-	Service *calendar.Service
-	ID      string
-	Logger  *log.Logger
+	Service   *calendar.Service
+	ID        string
+	Logger    *slog.Logger // Receives the logs of the calendar
+	Namespace string       // Separates the events of several Lectio accounts syncing to the same calendar. Empty for no namespace
+}
+
+// The counts of a calendar update
+type SyncResult struct {
+	Inserted int              `json:"inserted"`
+	Updated  int              `json:"updated"`
+	Deleted  int              `json:"deleted"`
+	Duration time.Duration    `json:"duration"`
+	Changes  []Change         `json:"changes,omitempty"`  // The changes of the modules, sorted by start time
+	Actions  []CalendarAction `json:"actions,omitempty"`  // The events inserted, updated and deleted
+	Failures []CalendarAction `json:"failures,omitempty"` // The events that could not be inserted, updated or deleted
+}
+
+// The kind of change made to a calendar event
+type ActionType string
+
+const (
+	ActionInsert ActionType = "insert"
+	ActionUpdate ActionType = "update"
+	ActionDelete ActionType = "delete"
+)
+
+// A change made to a calendar event by a sync
+type CalendarAction struct {
+	Type     ActionType `json:"type"`
+	ModuleID string     `json:"moduleId"`
+	EventID  string     `json:"eventId"`
+	Error    string     `json:"error,omitempty"` // Why the action failed. Empty for actions that succeeded
 }
 
 // Base Google Calendar event struct.
@@ -37,7 +69,7 @@ func NewGoogleCalendar(client *http.Client, calendarID string) (*GoogleCalendar,
 	calendar := &GoogleCalendar{
 		Service: service,
 		ID:      calendarID,
-		Logger:  log.New(os.Stdout, "google-calendar ", log.LstdFlags),
+		Logger:  slog.Default(),
 	}
 	return calendar, nil
 }
@@ -66,7 +98,7 @@ func (c *GoogleCalendar) GetEvents(weekCount int) (map[string]*GoogleEvent, erro
 			return nil, err
 		}
 		for _, item := range r.Items {
-			if strings.HasPrefix(item.Id, "lec") {
+			if c.ownsEvent(item.Id) {
 				wg.Add(1)
 				go func(item *calendar.Event) {
 					defer wg.Done()
@@ -89,10 +121,32 @@ func (c *GoogleCalendar) GetEvents(weekCount int) (map[string]*GoogleEvent, erro
 }
 
 // Updates the Google Calendar with the input Lectio modules and Google Calendar events. The modules input should not be filtered, as the functions handles that (input all modules from Lectio and all events from Google Calendar)
-func (c *GoogleCalendar) UpdateCalendar(lectioModules map[string]Module, googleEvents map[string]*GoogleEvent, hideCancelled bool) error {
-	var inserted int // For keeping track of inserted events count after execution
-	var updated int  // For keeping track of updated events count after execution
-	var deleted int  // For keeping track of deleted events count after execution
+func (c *GoogleCalendar) UpdateCalendar(lectioModules map[string]Module, googleEvents map[string]*GoogleEvent, hideCancelled bool) (*SyncResult, error) {
+	var inserted atomic.Int64 // For keeping track of inserted events count after execution
+	var updated atomic.Int64  // For keeping track of updated events count after execution
+	var deleted atomic.Int64  // For keeping track of deleted events count after execution
+
+	var changes []Change
+	var actions, failures []CalendarAction
+	var mu sync.Mutex
+	addChanges := func(c ...Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, c...)
+	}
+	addAction := func(actionType ActionType, moduleID, eventID string) {
+		mu.Lock()
+		defer mu.Unlock()
+		actions = append(actions, CalendarAction{Type: actionType, ModuleID: moduleID, EventID: eventID})
+	}
+	// A failing event does not stop the others, so it is recorded instead of returned
+	addFailure := func(actionType ActionType, moduleID, eventID string, err error) {
+		c.Logger.Warn("Could not change event", "action", actionType, "event", eventID, "error", err)
+		mu.Lock()
+		defer mu.Unlock()
+		failures = append(failures, CalendarAction{Type: actionType, ModuleID: moduleID, EventID: eventID, Error: err.Error()})
+	}
+	knownWeeks := syncedWeeks(googleEvents)
 
 	startTime := time.Now()
 	// Loops through each module in the Lectio schedule and checks for differences between it and the Google Calendar
@@ -102,47 +156,61 @@ func (c *GoogleCalendar) UpdateCalendar(lectioModules map[string]Module, googleE
 
 	for lectioKey, lectioModule := range lectioModules {
 		wg.Add(1)
-		go func(lKey string, lModule Module) error {
+		go func(lKey string, lModule Module) {
 			defer wg.Done()
 			// If Lectio module is in Google Calendar
-			key := "lec" + lKey
+			key := c.eventID(lKey)
 			if _, ok := googleEvents[key]; ok {
 				googleEvent := *googleEvents[key]
 				googleModule, err := googleEvent.ToModule()
 				if err != nil {
-					return err
+					addFailure(ActionUpdate, lKey, key, err)
+					return
 				}
+				googleModule.Id = lKey
 				needsUpdate := !lModule.Equals(googleModule)
-				if (hideCancelled && lModule.ModuleStatus == "Aflyst!" && googleEvent.Status != "cancelled") || (googleEvent.Status == "cancelled" && (!hideCancelled || lModule.ModuleStatus != "Aflyst!")) {
+				if (hideCancelled && lModule.ModuleStatus == StatusCancelled && googleEvent.Status != "cancelled") || (googleEvent.Status == "cancelled" && (!hideCancelled || lModule.ModuleStatus != StatusCancelled)) {
 					needsUpdate = true
 				}
 
 				if needsUpdate {
-					c.Logger.Printf("Attempting to update %v\n", googleEvent.Id)
+					c.Logger.Debug("Updating event", "event", googleEvent.Id)
 					lectioEvent := calendar.Event(*lModule.ToGoogleEvent())
+					lectioEvent.Id = key
 
-					if hideCancelled && lModule.ModuleStatus == "Aflyst!" {
+					if hideCancelled && lModule.ModuleStatus == StatusCancelled {
 						lectioEvent.Status = "cancelled"
 					} else {
 						lectioEvent.Status = "confirmed"
 					}
 					_, err := c.Service.Events.Update(c.ID, googleEvent.Id, &lectioEvent).Do()
 					if err != nil {
-						return err
+						addFailure(ActionUpdate, lKey, key, err)
+						return
 					}
-					updated++
-				} else {
-					return nil
+					updated.Add(1)
+					addAction(ActionUpdate, lKey, key)
+					addChanges(moduleChanges(lModule, googleModule)...)
 				}
 			} else {
+				c.Logger.Debug("Inserting event", "event", key)
 				googleEvent := calendar.Event(*lModule.ToGoogleEvent())
+				googleEvent.Id = key
 				_, err := c.Service.Events.Insert(c.ID, &googleEvent).Do()
 				if err != nil {
-					return err
+					addFailure(ActionInsert, lKey, key, err)
+					return
+				}
+				inserted.Add(1)
+				addAction(ActionInsert, lKey, key)
+				if knownWeeks[weekKey(lModule.StartDate)] {
+					changeType := ChangeNew
+					if lModule.ModuleStatus == StatusCancelled {
+						changeType = ChangeCancelled
+					}
+					addChanges(Change{Type: changeType, Module: lModule})
 				}
-				inserted++
 			}
-			return nil
 		}(lectioKey, lectioModule)
 	}
 
@@ -151,33 +219,84 @@ func (c *GoogleCalendar) UpdateCalendar(lectioModules map[string]Module, googleE
 	// Loops through all Google Events and checks if it should be deleted
 	for googleKey, googleEvent := range googleEvents {
 		wg.Add(1)
-		go func(googleKey string, googleEvent *GoogleEvent) error {
+		go func(googleKey string, googleEvent *GoogleEvent) {
 			defer wg.Done()
-			trimPrefix := strings.TrimPrefix(googleKey, "lec")
+			trimPrefix := strings.TrimPrefix(googleKey, c.eventPrefix())
 
 			if _, ok := lectioModules[trimPrefix]; !ok && googleEvent.Status != "cancelled" {
-				c.Logger.Printf("Attempting to delete %v\n", googleKey)
+				c.Logger.Debug("Deleting event", "event", googleKey)
 				err := c.Service.Events.Delete(c.ID, googleKey).Do()
 				if err != nil {
-					return err
+					addFailure(ActionDelete, trimPrefix, googleKey, err)
+					return
 				}
-				deleted++
+				deleted.Add(1)
+				addAction(ActionDelete, trimPrefix, googleKey)
 			}
-			return nil
 		}(googleKey, googleEvent)
 	}
 	wg.Wait()
 
-	fmt.Printf(`
+	sortChanges(changes)
+	sort.Slice(actions, func(i, j int) bool { return actions[i].EventID < actions[j].EventID })
+	sort.Slice(failures, func(i, j int) bool { return failures[i].EventID < failures[j].EventID })
+	result := &SyncResult{
+		Inserted: int(inserted.Load()),
+		Updated:  int(updated.Load()),
+		Deleted:  int(deleted.Load()),
+		Duration: time.Since(startTime),
+		Changes:  changes,
+		Actions:  actions,
+		Failures: failures,
+	}
+	return result, nil
+}
+
+// Returns the summary of the calendar update
+func (r *SyncResult) String() string {
+	changes := ""
+	if len(r.Changes) > 0 {
+		changes = "\nCHANGES\n"
+		for _, change := range r.Changes {
+			changes += change.String() + "\n"
+		}
+	}
+	failed := ""
+	if len(r.Failures) > 0 {
+		failed = fmt.Sprintf("FAILED to change %v events in Google Calendar\n", len(r.Failures))
+	}
+	return fmt.Sprintf(`
 RESULTS ==============================
 UPDATED %v events in Google Calendar
 INSERTED %v events into Google Calendar
 DELETED %v events from Google Calendar
-
+%s%s
 Execution took %v
 ======================================`,
-		updated, inserted, deleted, time.Since(startTime))
-	return nil
+		r.Updated, r.Inserted, r.Deleted, failed, changes, r.Duration)
+}
+
+// Returns the prefix of the IDs of the events created by lectigo. Namespaced prefixes are "lecn" followed by a hash
+// of fixed length, so that they never overlap each other or the plain "lec" prefix followed by a module ID
+func (c *GoogleCalendar) eventPrefix() string {
+	if c.Namespace == "" {
+		return "lec"
+	}
+	sum := sha1.Sum([]byte(c.Namespace))
+	return "lecn" + hex.EncodeToString(sum[:4])
+}
+
+// Returns the ID of the Google Calendar event of a Lectio module
+func (c *GoogleCalendar) eventID(moduleID string) string {
+	return c.eventPrefix() + moduleID
+}
+
+// Reports whether the event was created by lectigo in the namespace of the calendar
+func (c *GoogleCalendar) ownsEvent(eventID string) bool {
+	if c.Namespace == "" {
+		return strings.HasPrefix(eventID, "lec") && !strings.HasPrefix(eventID, "lecn")
+	}
+	return strings.HasPrefix(eventID, c.eventPrefix())
 }
 
 // Clears the Google Calendar of Lectigo events
@@ -198,7 +317,7 @@ func (c *GoogleCalendar) Clear() error {
 			return err
 		}
 		for _, item := range r.Items {
-			if strings.Contains(item.Id, "lec") {
+			if strings.Contains(item.Id, "lec") && (c.Namespace == "" || c.ownsEvent(item.Id)) {
 				wg.Add(1)
 				go func(item *calendar.Event) error {
 					defer wg.Done()
@@ -218,7 +337,7 @@ func (c *GoogleCalendar) Clear() error {
 		}
 	}
 	wg.Wait()
-	log.Printf("Found and deleted %v events in %v\n", eventCount, time.Since(s))
+	c.Logger.Info("Cleared Google Calendar", "deleted", eventCount, "duration", time.Since(s))
 	return nil
 }
 
@@ -228,12 +347,19 @@ func (e *GoogleEvent) ToModule() (*Module, error) {
 	if err != nil {
 		return nil, err
 	}
-	start, err := time.ParseInLocation(time.RFC3339, e.Start.DateTime, location)
+	// All-day events only carry a date, and their end date is exclusive
+	allDay := e.Start.Date != ""
+	layout, startValue, endValue := time.RFC3339, e.Start.DateTime, e.End.DateTime
+	if allDay {
+		layout, startValue, endValue = time.DateOnly, e.Start.Date, e.End.Date
+	}
+
+	start, err := time.ParseInLocation(layout, startValue, location)
 	if err != nil {
 		return nil, err
 	}
 
-	end, err := time.ParseInLocation(time.RFC3339, e.End.DateTime, location)
+	end, err := time.ParseInLocation(layout, endValue, location)
 	if err != nil {
 		return nil, err
 	}
@@ -245,7 +371,25 @@ func (e *GoogleEvent) ToModule() (*Module, error) {
 		EndDate:      end,
 		Location:     e.Location,
 		Description:  e.Description,
-		ModuleStatus: util.StatusFromColorID(e.ColorId),
+		ModuleStatus: StatusFromColorID(e.ColorId),
+		AllDay:       allDay,
+		Holiday:      allDay && e.Transparency == "transparent",
+		Color:        e.ColorId,
+		Visibility:   e.Visibility,
+	}
+	if e.Visibility == "default" {
+		module.Visibility = ""
+	}
+	if e.ExtendedProperties != nil {
+		if status, ok := e.ExtendedProperties.Private[statusProperty]; ok {
+			module.ModuleStatus = ModuleStatus(status)
+		}
+	}
+
+	if e.Reminders != nil && !e.Reminders.UseDefault {
+		for _, reminder := range e.Reminders.Overrides {
+			module.Reminders = append(module.Reminders, int(reminder.Minutes))
+		}
 	}
 
 	return module, nil
@@ -4,10 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -26,25 +27,88 @@ type LectioLoginInfo struct {
 	SchoolID string `json:"schoolID"`
 }
 
+// Logs the login info without its password
+func (i *LectioLoginInfo) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("username", i.Username),
+		slog.String("schoolID", i.SchoolID),
+	)
+}
+
 type Lectio struct {
-	Context   context.Context
-	Cancel    context.CancelFunc
-	LoginInfo *LectioLoginInfo
-	DecodeMap map[string]string
-	Blacklist *[]ClassesToIgnore
+	Context       context.Context
+	Cancel        context.CancelFunc
+	LoginInfo     *LectioLoginInfo
+	Abbreviations *Abbreviations // Decodes the teams of modules into the titles of their subjects. Nil leaves teams undecoded
+	Blacklist     []FilterRule   // Modules matching any of the rules are ignored. Made from the classes to ignore by BlacklistRules
+	Filter        *Filter        // Decides which modules are kept after the blacklist. Nil keeps every module
+	Options       ScrapeOptions  // How requests to Lectio are made
+	Logger        *slog.Logger   // Receives the logs of the instance. Nil uses slog.Default()
+
+	// Called with every scraped module and its raw title before the blacklist and filter are applied, eg. to explain
+	// which modules they drop. Weeks are scraped concurrently, so it must be safe for concurrent use. Nil for none
+	Scraped func(m Module, title string)
+
+	limiter *limiter
+	auth    Authenticator
+	teams   teamIDs // The IDs of the teams found in scraped schedules, for learning their titles
 }
 
 type Module struct {
-	Id           string    `json:"id"`          // The ID of the module
-	Title        string    `json:"title"`       // Title of the module (eg. 3a Dansk)
-	StartDate    time.Time `json:"startDate"`   // The start date of the module. This includes the date as well as the time of start (eg. 09:55)
-	EndDate      time.Time `json:"endDate"`     // The end date of the module. This includes the date as well as the time of end (eg. 11:25)
-	Location     string    `json:"location"`    // The room of the module (eg. 22)
-	Teacher      string    `json:"teacher"`     // The teacher of the class
-	Group        string    `json:"group"`       // The group assigned the class (e.g. 2a MU)
-	Homework     string    `json:"homework"`    // Homework for the module
-	Description  string    `json:"description"` // Notes and description by the teacher
-	ModuleStatus string    `json:"status"`      // The status of the module (eg. "Ændret" or "Aflyst")
+	Id           string       `json:"id"`          // The ID of the module
+	Title        string       `json:"title"`       // Title of the module (eg. 3a Dansk)
+	StartDate    time.Time    `json:"startDate"`   // The start date of the module. This includes the date as well as the time of start (eg. 09:55)
+	EndDate      time.Time    `json:"endDate"`     // The end date of the module. This includes the date as well as the time of end (eg. 11:25)
+	Location     string       `json:"location"`    // The rooms of the module as shown in Lectio (eg. "Lokaler: 22, 23"). Derived from Rooms
+	Teacher      string       `json:"teacher"`     // The teachers of the class as shown in Lectio (eg. "Lærere: ABC, DEF"). Derived from Teachers
+	Group        string       `json:"group"`       // The decoded name of the teams assigned the class (e.g. Musik)
+	Teachers     []Teacher    `json:"teachers"`    // The teachers of the class
+	Rooms        []string     `json:"rooms"`       // The rooms of the module (eg. 22)
+	Teams        []string     `json:"teams"`       // The teams ("Hold") assigned the class (eg. 2a MU)
+	Homework     string       `json:"homework"`    // Homework for the module
+	Description  string       `json:"description"` // Notes and description by the teacher
+	Materials    string       `json:"materials"`   // Other content ("Øvrigt indhold") of the module. Only filled by FetchModuleDetails
+	Attachments  []Attachment `json:"attachments"` // Files and links attached to the module. Only filled by FetchModuleDetails
+	ModuleStatus ModuleStatus `json:"status"`      // The status of the module (eg. changed or cancelled)
+	AllDay       bool         `json:"allDay"`      // Whether the module spans whole days (eg. excursions, holidays and day notes). The end date is exclusive
+	Holiday      bool         `json:"holiday"`     // Whether the module marks a holiday or a day without teaching
+	Exam         bool         `json:"exam"`        // Whether the module is an exam
+	Reminders    []int        `json:"reminders"`   // Minutes before the start of the module to remind at. Empty uses the calendar default
+	Color        string       `json:"color"`       // The Google Calendar colour ID of the event, overriding the colour of the status. Set by transform rules
+	Visibility   string       `json:"visibility"`  // The visibility of the event (public, private or confidential). Empty for the calendar default
+
+	rawTitle string // The title as shown in Lectio, before the teams are decoded
+}
+
+// A teacher of a module. The name is only available when Lectio shows it, which is usually the case when a module has a single teacher
+type Teacher struct {
+	Initials string `json:"initials"`       // The initials of the teacher (eg. ABC)
+	Name     string `json:"name,omitempty"` // The full name of the teacher (eg. Anders Bent Christensen)
+}
+
+// Expressions for matching and splitting the dates in a module tooltip
+var (
+	reDateMatch   = regexp.MustCompile(`(\d{1,2}\/\d{1,2}-20\d{2}\s\d{2}:\d{2}\s(?:til|to)\s\d{2}:\d{2})`)
+	reDateSplit   = regexp.MustCompile(`\/|-|:+|\s+`)
+	reTeacher     = regexp.MustCompile(`^(.+?)\s*\(([^()]+)\)$`)
+	reAllDayMatch = regexp.MustCompile(`^(\d{1,2}\/\d{1,2}-20\d{2})(?:\s+(?:Hele dagen|All day)|\s+(?:til|to)\s+(\d{1,2}\/\d{1,2}-20\d{2}))$`)
+)
+
+// The kind of schedule to fetch from Lectio
+type ScheduleType string
+
+const (
+	ScheduleSelf    ScheduleType = ""           // The schedule of the logged in user
+	ScheduleStudent ScheduleType = "elev"       // The schedule of a student
+	ScheduleTeacher ScheduleType = "laerer"     // The schedule of a teacher
+	ScheduleRoom    ScheduleType = "lokale"     // The schedule of a room
+	ScheduleClass   ScheduleType = "stamklasse" // The schedule of a class
+)
+
+// The schedule to fetch from Lectio. The zero value is the schedule of the logged in user
+type ScheduleTarget struct {
+	Type ScheduleType `json:"type"`
+	ID   string       `json:"id"`
 }
 
 type ClassesToIgnore struct {
@@ -53,66 +117,70 @@ type ClassesToIgnore struct {
 	ExactMatches []string `yaml:"exactMatches"`
 }
 
-func NewLectio(loginInfo *LectioLoginInfo, decodeClasses bool) (*Lectio, error) {
-	loginUrl := fmt.Sprintf("https://www.lectio.dk/lectio/%s/login.aspx", loginInfo.SchoolID)
+// Creates a new Lectio instance logged in at the school of loginInfo using the authenticator.
+// If auth is nil, the username and password of loginInfo are used. If opts is nil, DefaultScrapeOptions are used.
+// The instance has no abbreviations and no blacklist until Abbreviations and Blacklist are set. Its logs, including those
+// of logging in, go to logger. If logger is nil, slog.Default() is used
+func NewLectio(loginInfo *LectioLoginInfo, auth Authenticator, opts *ScrapeOptions, logger *slog.Logger) (*Lectio, error) {
+	if auth == nil {
+		auth = &PasswordAuthenticator{}
+	}
+	if opts == nil {
+		defaults := DefaultScrapeOptions()
+		opts = &defaults
+	}
 
-	ctx, cancel := chromedp.NewContext(context.Background())
+	allocatorOptions := chromedp.DefaultExecAllocatorOptions[:]
+	if opts.UserAgent != "" {
+		allocatorOptions = append(allocatorOptions, chromedp.UserAgent(opts.UserAgent))
+	}
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), allocatorOptions...)
+	ctx, cancelCtx := chromedp.NewContext(allocCtx)
+	cancel := func() {
+		cancelCtx()
+		cancelAlloc()
+	}
 
-	loginTask := chromedp.Tasks{
-		chromedp.Navigate(loginUrl),
-		chromedp.WaitVisible("#username"),
-		chromedp.SendKeys("#username", loginInfo.Username),
-		chromedp.SendKeys("#password", loginInfo.Password),
-		chromedp.Click("#m_Content_submitbtn2", chromedp.NodeVisible),
+	lectio := &Lectio{
+		Context:   ctx,
+		Cancel:    cancel,
+		LoginInfo: loginInfo,
+		Options:   *opts,
+		Logger:    logger,
+		limiter:   newLimiter(opts.RequestsPerSecond),
+		auth:      auth,
 	}
 
-	err := chromedp.Run(ctx, loginTask)
+	err := auth.Authenticate(lectio)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
+	return lectio, nil
+}
 
-	abbreviations := make(map[string]string)
-
-	if decodeClasses {
-		ymlFile, err := os.ReadFile("abbreviations.yml")
-		if err != nil {
-			return nil, err
-		}
-		yaml.Unmarshal(ymlFile, abbreviations)
-	}
-
+// Reads the classes to ignore from a YAML file
+func LoadBlacklist(path string) (*[]ClassesToIgnore, error) {
 	toIgnore := &[]ClassesToIgnore{}
-	ymlFile, err := os.ReadFile("blacklist.yml")
+	ymlFile, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
 	err = yaml.Unmarshal(ymlFile, toIgnore)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("could not parse blacklist file %q: %w", path, err)
 	}
-
-	lectio := &Lectio{
-		Context:   ctx,
-		Cancel:    cancel,
-		LoginInfo: loginInfo,
-		DecodeMap: abbreviations,
-		Blacklist: toIgnore,
+	_, err = BlacklistRules(*toIgnore)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse blacklist file %q: %w", path, err)
 	}
-	return lectio, nil
+	return toIgnore, nil
 }
 
 // Converts a Lectio module to a Google Calendar event
 func (m *Module) ToGoogleEvent() *GoogleEvent {
-	calendarColorID := ""
-	switch m.ModuleStatus {
-	case "Aflyst!":
-		calendarColorID = "4"
-	case "Ændret!":
-		calendarColorID = "2"
-	}
-
-	return &GoogleEvent{
+	event := &GoogleEvent{
 		Id:          "lec" + m.Id,
 		Description: createEventDescription(m),
 		Start: &calendar.EventDateTime{
@@ -123,162 +191,391 @@ func (m *Module) ToGoogleEvent() *GoogleEvent {
 			DateTime: m.EndDate.Format(time.RFC3339),
 			TimeZone: "Europe/Copenhagen",
 		},
-		Location: m.Location,
-		Summary:  m.Title,
-		ColorId:  calendarColorID,
-		Status:   "confirmed",
+		Location:   m.Location,
+		Summary:    m.Title,
+		ColorId:    m.colorID(),
+		Visibility: m.Visibility,
+		Status:     "confirmed",
 	}
+
+	if m.Color != "" {
+		// The status can not be read from the colour of the event, so it is kept with the event
+		event.ExtendedProperties = &calendar.EventExtendedProperties{
+			Private: map[string]string{statusProperty: string(m.ModuleStatus)},
+		}
+	}
+
+	if m.AllDay {
+		event.Start = &calendar.EventDateTime{Date: m.StartDate.Format(time.DateOnly)}
+		event.End = &calendar.EventDateTime{Date: m.EndDate.Format(time.DateOnly)}
+		if m.Holiday {
+			event.Transparency = "transparent"
+		}
+	}
+
+	if len(m.Reminders) > 0 {
+		event.Reminders = &calendar.EventReminders{ForceSendFields: []string{"UseDefault"}}
+		for _, minutes := range m.Reminders {
+			event.Reminders.Overrides = append(event.Reminders.Overrides, &calendar.EventReminder{Method: "popup", Minutes: int64(minutes)})
+		}
+	}
+	return event
 }
 
-func (l *Lectio) GetSchedule(week int) (map[string]Module, error) {
-	modules := make(map[string]Module)
+// Returns the Google Calendar colour ID of the module. Exams are highlighted unless their status has a colour, and a
+// colour set by a transform rule overrides both
+func (m *Module) colorID() string {
+	if m.Color != "" {
+		return m.Color
+	}
+	if colorID := m.ModuleStatus.ColorID(); colorID != "" || !m.Exam {
+		return colorID
+	}
+	return colorIDExam
+}
+
+// Returns the query parameters selecting the target schedule on SkemaNy.aspx
+func (t ScheduleTarget) query() (url.Values, error) {
+	query := url.Values{}
+	if t.Type == ScheduleSelf {
+		return query, nil
+	}
+
+	var idParam string
+	switch t.Type {
+	case ScheduleStudent:
+		idParam = "elevid"
+	case ScheduleTeacher:
+		idParam = "laererid"
+	case ScheduleRoom:
+		idParam = "lokaleid"
+	case ScheduleClass:
+		idParam = "klasseid"
+	default:
+		return nil, fmt.Errorf("unknown schedule type %q", t.Type)
+	}
+	if t.ID == "" {
+		return nil, fmt.Errorf("no ID given for schedule type %q", t.Type)
+	}
+
+	query.Set("type", string(t.Type))
+	query.Set(idParam, t.ID)
+	return query, nil
+}
+
+// Gets the Lectio schedule of the target in the given week of the current year
+func (l *Lectio) GetSchedule(week int, target ScheduleTarget) (map[string]Module, error) {
+	return l.getSchedule(l.Context, time.Now().Year(), week, target)
+}
+
+// Gets the Lectio schedule of the target in the given week of the given year
+func (l *Lectio) GetScheduleOfYear(year, week int, target ScheduleTarget) (map[string]Module, error) {
+	return l.getSchedule(l.Context, year, week, target)
+}
 
-	weekString := fmt.Sprintf("%v%v", week, time.Now().Year())
-	scheduleUrl := fmt.Sprintf("https://www.lectio.dk/lectio/%s/SkemaNy.aspx?week=%v", l.LoginInfo.SchoolID, weekString)
+// Gets the Lectio schedule of the target in the given week using the browser tab of ctx
+func (l *Lectio) getSchedule(ctx context.Context, year, week int, target ScheduleTarget) (map[string]Module, error) {
+	query, err := target.query()
+	if err != nil {
+		return nil, err
+	}
+	query.Set("week", fmt.Sprintf("%v%v", week, year))
+	scheduleUrl := fmt.Sprintf("https://www.lectio.dk/lectio/%s/SkemaNy.aspx?%s", l.LoginInfo.SchoolID, query.Encode())
 	const selector string = "#s_m_Content_Content_SkemaMedNavigation_skema_skematabel"
 
 	// Check if login was successful by navigating to schedule
 	var searchNodes []*cdp.Node
 	scheduleTask := chromedp.Tasks{
 		chromedp.WaitReady("body"),
-		chromedp.Navigate(scheduleUrl),
 		chromedp.Nodes(selector, &searchNodes, chromedp.AtLeast(0)),
 	}
-	err := chromedp.Run(l.Context, scheduleTask)
+	err = l.navigate(ctx, scheduleUrl)
+	if err != nil {
+		return nil, err
+	}
+	err = chromedp.Run(ctx, scheduleTask)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get HTML of schedule table by using chromedp
 	var scheduleHTML string
-	err = chromedp.Run(l.Context, chromedp.InnerHTML(selector, &scheduleHTML))
+	err = chromedp.Run(ctx, chromedp.InnerHTML(selector, &scheduleHTML))
 	if err != nil {
 		return nil, err
 	}
 
+	return l.parseSchedule(scheduleHTML, year)
+}
+
+// Parses the HTML of a schedule table into modules, including all-day items and day notes
+func (l *Lectio) parseSchedule(scheduleHTML string, year int) (map[string]Module, error) {
+	modules := make(map[string]Module)
+
 	doc, err := html.Parse(strings.NewReader(scheduleHTML))
 	if err != nil {
 		return nil, err
 	}
 
-	// Expressions for matching and splitting time format
-	reDateMatch := regexp.MustCompile(`(\d{1,2}\/\d{1,2}-20\d{2}\s\d{2}:\d{2}\stil\s\d{2}:\d{2})`)
-	reDateSplit := regexp.MustCompile(`\/|-|:+|\s+`)
+	dayDates := parseDayHeaders(doc, year)
 
-	// Find all <a> elements
-	var getAllModules func(n *html.Node)
-	getAllModules = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "a" && len(n.Attr) == 6 {
+	// Find all module bricks and the all-day items in the info header
+	var getAllModules func(n *html.Node, day int)
+	getAllModules = func(n *html.Node, day int) {
+		if n.Type == html.ElementNode && n.Data == "td" && hasClass(n, "s2infoHeader") {
+			day = columnIndex(n)
+			if note := dayNoteText(n); note != "" && day < len(dayDates) && !dayDates[day].IsZero() {
+				module := newDayNote(note, dayDates[day])
+				modules[module.Id] = module
+			}
+		}
 
-			var module Module
-			var title string
+		if tooltip, ok := getAttr(n, "data-tooltip"); ok && n.Type == html.ElementNode && hasClass(n, "s2skemabrik") {
+			var id string
+			if href, ok := getAttr(n, "href"); ok && strings.Contains(href, "?") {
+				// Extract ID from URL of the module
+				params, _ := url.ParseQuery(strings.Split(href, "?")[1])
+				id = params.Get("absid")
+			}
 
-			// Extract ID from URL of the module
-			params, _ := url.ParseQuery(strings.Split(n.Attr[0].Val, "?")[1])
-			module.Id = params.Get("absid")
+			l.collectTeamIDs(n)
+			module, title, err := l.parseModule(tooltip)
+			if err != nil {
+				l.logger().Warn("Could not parse module", "id", id, "error", err)
+				return
+			}
+
+			if module.StartDate.IsZero() && day >= 0 && day < len(dayDates) {
+				// All-day items without a date in the tooltip belong to the column they are placed in
+				module.StartDate = dayDates[day]
+				module.EndDate = dayDates[day].AddDate(0, 0, 1)
+				module.AllDay = true
+			}
 
-			if n.Attr[4].Key != "data-tooltip" {
-				log.Fatalln("unable to find module key when scraping lectio")
+			if module.StartDate.IsZero() {
 				return
 			}
 
-			// Get module details/elements
-			moduleElements := strings.Split(n.Attr[4].Val, "\n")
-
-			// Loop over all elements of current module
-			for i := 0; i != len(moduleElements); i++ {
-
-				if reDateMatch.Match([]byte(moduleElements[i])) {
-					// Check element for assigned time and date
-					module.StartDate, module.EndDate, err = util.ParseTimeAndDate(moduleElements[i], reDateSplit)
-
-					if err != nil {
-						fmt.Println("Failed to parse date and time")
-						return
-					}
-
-				} else if moduleElements[i] == "Ændret!" || moduleElements[i] == "Aflyst!" {
-					// Check for status on module
-					module.ModuleStatus = moduleElements[i]
-
-				} else if strings.HasPrefix(moduleElements[i], "Lærere: ") || strings.HasPrefix(moduleElements[i], "Lærer: ") {
-					// Check for assigned teachers
-					module.Teacher = moduleElements[i]
-
-				} else if strings.HasPrefix(moduleElements[i], "Lokale: ") || strings.HasPrefix(moduleElements[i], "Lokaler: ") {
-					// Check for assigned location
-					module.Location = moduleElements[i]
-
-				} else if strings.HasPrefix(moduleElements[i], "Hold: ") {
-					// Check for group assigned to lesson
-					moduleGroup := strings.TrimPrefix(moduleElements[i], "Hold: ")
-
-					// Decode abbreviations and create title for event
-					var ok bool
-					if module.Group, ok = l.DecodeMap[moduleGroup]; !ok {
-						if module.Title != "" {
-							module.Title += " - "
-						}
-						module.Title += moduleGroup
-
-					} else if module.Title != "" {
-						module.Title = fmt.Sprintf("%s: %s", module.Group, module.Title)
-					} else {
-						module.Title = module.Group
-					}
-
-				} else if moduleElements[i] == "Lektier:" {
-					// Check for homework for the lesson
-
-					for j := i + 1; j != len(moduleElements); j++ {
-						if !strings.HasPrefix(moduleElements[j], "Note:") {
-							module.Homework += moduleElements[j] + "\n"
-							i = j
-						} else {
-							break
-						}
-					}
-
-				} else if moduleElements[i] == "Note:" {
-					// Check for description and notes of the lesson
-					for j := i + 1; j != len(moduleElements); j++ {
-						module.Description += moduleElements[j] + "\n"
-						i = j
-					}
-
-				} else if moduleElements[i] != "" && !strings.HasPrefix(moduleElements[i], "Elever: ") && i < 2 {
-					// Assign as title if no other match
-					module.Title = moduleElements[i]
-					title = moduleElements[i]
-				}
+			module.Id = id
+			if module.Id == "" {
+				module.Id = dayNoteID(module.StartDate, title)
 			}
-			if !l.isClassBlacklisted(title, module.StartDate) {
+			if module.AllDay {
+				module.Holiday = isHolidayTitle(title)
+			}
+
+			if l.Scraped != nil {
+				l.Scraped(module, title)
+			}
+			if l.keepsModule(&module, title) {
 				modules[module.Id] = module
 			}
+			return
 		}
 
 		// Loop to next module until week is done
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			getAllModules(c)
+			getAllModules(c, day)
 		}
 	}
-	getAllModules(doc)
+	getAllModules(doc, -1)
 	return modules, nil
 }
 
-// Gets the Lectio schedule from the current weeks and weekCount weeks ahead.
-func (l *Lectio) GetScheduleWeeks(weekCount int) (modules map[string]Module, err error) {
-	modules = make(map[string]Module)
+// Parses the tooltip of a schedule brick into a module. The returned title is the raw, undecoded title of the module
+func (l *Lectio) parseModule(tooltip string) (module Module, title string, err error) {
+	// Get module details/elements
+	moduleElements := strings.Split(tooltip, "\n")
+
+	// Loop over all elements of current module
+	for i := 0; i != len(moduleElements); i++ {
+
+		if reDateMatch.Match([]byte(moduleElements[i])) {
+			// Check element for assigned time and date
+			module.StartDate, module.EndDate, err = util.ParseTimeAndDate(moduleElements[i], reDateSplit)
+			if err != nil {
+				return module, title, fmt.Errorf("could not parse date and time %q: %w", moduleElements[i], err)
+			}
+
+		} else if reAllDayMatch.MatchString(moduleElements[i]) {
+			// Check element for all-day or multi-day dates
+			module.StartDate, module.EndDate, err = util.ParseAllDayDates(reAllDayMatch.FindStringSubmatch(moduleElements[i]))
+			if err != nil {
+				return module, title, fmt.Errorf("could not parse all-day date %q: %w", moduleElements[i], err)
+			}
+			module.AllDay = true
+
+		} else if status, ok := statusFromLabel(moduleElements[i]); ok {
+			// Check for status on module
+			module.ModuleStatus = status
+
+		} else if hasLabel(moduleElements[i], teacherLabels) {
+			// Check for assigned teachers
+			module.Teachers = parseTeachers(moduleElements[i])
+			module.Teacher = formatTeachers(module.Teachers)
+
+		} else if hasLabel(moduleElements[i], roomLabels) {
+			// Check for assigned location
+			module.Rooms = splitList(moduleElements[i])
+			module.Location = formatRooms(module.Rooms)
+
+		} else if hasLabel(moduleElements[i], teamLabels) {
+			// Check for teams assigned to lesson
+			module.Teams = append(module.Teams, splitList(moduleElements[i])...)
+
+		} else if isLabel(moduleElements[i], homeworkLabels) {
+			// Check for homework for the lesson
+
+			for j := i + 1; j != len(moduleElements); j++ {
+				if !hasLabel(moduleElements[j], noteLabels) {
+					module.Homework += moduleElements[j] + "\n"
+					i = j
+				} else {
+					break
+				}
+			}
+
+		} else if isLabel(moduleElements[i], noteLabels) {
+			// Check for description and notes of the lesson
+			for j := i + 1; j != len(moduleElements); j++ {
+				module.Description += moduleElements[j] + "\n"
+				i = j
+			}
+
+		} else if moduleElements[i] != "" && !hasLabel(moduleElements[i], studentLabels) && i < 2 {
+			// Assign as title if no other match
+			module.Title = moduleElements[i]
+			title = moduleElements[i]
+		}
+	}
+	module.rawTitle = title
+	l.setModuleTitle(&module)
+	return module, title, nil
+}
+
+// Decodes the teams of the module and creates the title of the event from them
+func (l *Lectio) setModuleTitle(module *Module) {
+	if len(module.Teams) == 0 {
+		return
+	}
+
+	var groups []string
+	for _, team := range module.Teams {
+		if group, ok := l.Abbreviations.Decode(team); ok && !slices.Contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+
+	if len(groups) == 0 {
+		if module.Title != "" {
+			module.Title += " - "
+		}
+		module.Title += strings.Join(module.Teams, ", ")
+		return
+	}
+
+	module.Group = strings.Join(groups, ", ")
+	if module.Title != "" {
+		module.Title = fmt.Sprintf("%s: %s", module.Group, module.Title)
+	} else {
+		module.Title = module.Group
+	}
+}
+
+// Splits a comma separated list in a module element, removing the prefix (eg. "Lokaler: 22, 23")
+func splitList(element string) []string {
+	_, list, _ := strings.Cut(element, ": ")
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Parses the teachers of a module element (eg. "Lærer: Anders Bent Christensen (ABC)" or "Lærere: ABC, DEF")
+func parseTeachers(element string) []Teacher {
+	var teachers []Teacher
+	for _, item := range splitList(element) {
+		if matches := reTeacher.FindStringSubmatch(item); matches != nil {
+			teachers = append(teachers, Teacher{Initials: matches[2], Name: matches[1]})
+		} else {
+			teachers = append(teachers, Teacher{Initials: item})
+		}
+	}
+	return teachers
+}
+
+// Formats teachers the way Lectio shows them in the schedule
+func formatTeachers(teachers []Teacher) string {
+	if len(teachers) == 0 {
+		return ""
+	}
+
+	items := make([]string, len(teachers))
+	for i, teacher := range teachers {
+		items[i] = teacher.String()
+	}
+
+	if len(teachers) == 1 {
+		return "Lærer: " + items[0]
+	}
+	return "Lærere: " + strings.Join(items, ", ")
+}
+
+// Formats rooms the way Lectio shows them in the schedule
+func formatRooms(rooms []string) string {
+	switch len(rooms) {
+	case 0:
+		return ""
+	case 1:
+		return "Lokale: " + rooms[0]
+	}
+	return "Lokaler: " + strings.Join(rooms, ", ")
+}
+
+// Returns the teacher as shown in Lectio, ie. "Name (Initials)" or just the initials
+func (t Teacher) String() string {
+	if t.Name == "" {
+		return t.Initials
+	}
+	return fmt.Sprintf("%s (%s)", t.Name, t.Initials)
+}
+
+// Returns the logger of the instance
+func (l *Lectio) logger() *slog.Logger {
+	if l.Logger == nil {
+		return slog.Default()
+	}
+	return l.Logger
+}
+
+// Gets the Lectio schedule of the target from the current weeks and weekCount weeks ahead.
+// The weeks are fetched concurrently in a pool of l.Options.Tabs browser tabs. If any week fails, the remaining weeks are
+// abandoned and the error is returned
+func (l *Lectio) GetScheduleWeeks(weekCount int, target ScheduleTarget) (modules map[string]Module, err error) {
 	_, week := time.Now().ISOWeek()
 
-	for i := 0; i < weekCount; i++ {
-		weekModules, err := l.GetSchedule(week + i)
+	weekModules := make([]map[string]Module, weekCount)
+	err = l.runInTabs(weekCount, func(ctx context.Context, i int) error {
+		m, err := l.getSchedule(ctx, time.Now().Year(), week+i, target)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("could not get schedule of week %v: %w", week+i, err)
 		}
-		maps.Copy(modules, weekModules)
+		weekModules[i] = m
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	fmt.Printf("MODULES: %#v\n", modules)
+
+	// Weeks are merged in order, so the result does not depend on which tab finished first
+	modules = make(map[string]Module)
+	for _, m := range weekModules {
+		maps.Copy(modules, m)
+	}
+	l.logger().Debug("Scraped Lectio schedule", "weeks", weekCount, "modules", len(modules))
 	return modules, nil
 }
 
@@ -288,12 +585,24 @@ func (m1 *Module) Equals(m2 *Module) bool {
 		m1.Title == m2.Title &&
 		m1.StartDate.Equal(m2.StartDate) &&
 		m1.EndDate.Equal(m2.EndDate) &&
+		m1.AllDay == m2.AllDay &&
 		m1.ModuleStatus == m2.ModuleStatus &&
 		m1.Location == m2.Location &&
+		m1.colorID() == m2.colorID() &&
+		m1.Visibility == m2.Visibility &&
+		remindersEqual(m1.Reminders, m2.Reminders) &&
 		createEventDescription(m1) == m2.Description
 	return b
 }
 
+// Checks if two lists of reminders contain the same reminders, regardless of order
+func remindersEqual(r1, r2 []int) bool {
+	r1, r2 = slices.Clone(r1), slices.Clone(r2)
+	slices.Sort(r1)
+	slices.Sort(r2)
+	return slices.Equal(r1, r2)
+}
+
 // Converts input Lectio modules to a JSON object at the specified path
 func ModulesToJSON(modules map[string]Module, filename string) error {
 	filename, _ = strings.CutSuffix(filename, ".json")
@@ -310,7 +619,35 @@ func ModulesToJSON(modules map[string]Module, filename string) error {
 	return nil
 }
 
+// Returns the rooms of the module, eg. "22, 23"
+func (m *Module) RoomNames() string {
+	if len(m.Rooms) > 0 {
+		return strings.Join(m.Rooms, ", ")
+	}
+	return m.Location
+}
+
+// Returns the initials of the teachers of the module, eg. "ABC, DEF"
+func (m *Module) TeacherInitials() string {
+	if len(m.Teachers) == 0 {
+		return m.Teacher
+	}
+	initials := make([]string, len(m.Teachers))
+	for i, teacher := range m.Teachers {
+		initials[i] = teacher.Initials
+	}
+	return strings.Join(initials, ", ")
+}
+
+// Returns the description of the module as shown in its calendar event
+func (m *Module) EventDescription() string {
+	return createEventDescription(m)
+}
+
 func createEventDescription(m *Module) string {
+	if m.AllDay && m.Teacher == "" {
+		return m.Description
+	}
 	description := m.Teacher + "\n"
 	if m.Description != "" {
 		description += fmt.Sprintf("Noter: %s", m.Description)
@@ -318,26 +655,28 @@ func createEventDescription(m *Module) string {
 	if m.Homework != "" {
 		description += fmt.Sprintf("Lektier:\n%s", m.Homework)
 	}
+	if m.Materials != "" {
+		description += fmt.Sprintf("Øvrigt indhold:\n%s\n", m.Materials)
+	}
+	if len(m.Attachments) > 0 {
+		description += "Materialer:\n"
+		for _, attachment := range m.Attachments {
+			description += fmt.Sprintf("%s: %s\n", attachment.Name, attachment.URL)
+		}
+	}
 	return description
 }
 
-// Checks if title contains blacklisted keywords after given time
-func (l *Lectio) isClassBlacklisted(title string, startDate time.Time) bool {
-	for _, ignorePastTime := range *l.Blacklist {
-		ignoreTime, _ := time.Parse("1504", ignorePastTime.Time)
-		moduleTime := time.Date(0, 1, 1, startDate.Hour(), startDate.Minute(), 0, 0, time.UTC)
+// Reports whether the module is neither blacklisted nor excluded by the filter
+func (l *Lectio) keepsModule(m *Module, title string) bool {
+	return !l.isClassBlacklisted(m, title) && l.Filter.Includes(m, title)
+}
 
-		if moduleTime.Compare(ignoreTime) >= 0 {
-			for _, keyword := range ignorePastTime.Keywords {
-				if strings.Contains(strings.ToLower(title), keyword) {
-					return true
-				}
-			}
-			for _, exactMatch := range ignorePastTime.ExactMatches {
-				if title == exactMatch {
-					return true
-				}
-			}
+// Checks if the title of the module contains blacklisted keywords after the time of the blacklist
+func (l *Lectio) isClassBlacklisted(m *Module, title string) bool {
+	for i := range l.Blacklist {
+		if l.Blacklist[i].Matches(m, title) {
+			return true
 		}
 	}
 	return false
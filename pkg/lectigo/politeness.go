@@ -0,0 +1,210 @@
+package lectigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	DefaultTabs              = 3                           // The default amount of browser tabs fetching weeks concurrently
+	DefaultRequestsPerSecond = 2                           // The default maximum amount of requests per second to Lectio
+	DefaultMaxRetries        = 3                           // The default amount of retries of a failed request to Lectio
+	DefaultRetryBackoff      = 2 * time.Second             // The default wait before the first retry of a failed request
+	maxRetryBackoff          = 30 * time.Second            // The longest wait between two retries
+	maintenanceText          = "midlertidigt utilgængelig" // The title of the page shown by Lectio when it is down for maintenance
+)
+
+// Returns the title and the headings of the page, where the maintenance page shows maintenanceText. The rest of the
+// page is left out, as notes and messages on ordinary pages may contain the same words
+const pageHeadingsScript = `[document.title, ...Array.from(document.querySelectorAll("h1, h2"), e => e.innerText)].join("\n")`
+
+// Returned when Lectio shows its maintenance page
+var ErrLectioUnavailable = errors.New("Lectio is temporarily unavailable")
+
+// Options for how requests to Lectio are made, so that scheduled syncs are resilient and do not hammer the
+// server of the school
+type ScrapeOptions struct {
+	Tabs              int           // The amount of browser tabs fetching weeks concurrently
+	RequestsPerSecond float64       // The maximum amount of requests per second across all tabs. Zero or less disables the limit
+	MaxRetries        int           // The amount of retries of a request that failed transiently (eg. 503 or maintenance)
+	RetryBackoff      time.Duration // The wait before the first retry. It doubles for every following retry
+	UserAgent         string        // The User-Agent sent to Lectio. Empty uses the default of the browser
+}
+
+// Returns the default scrape options
+func DefaultScrapeOptions() ScrapeOptions {
+	return ScrapeOptions{
+		Tabs:              DefaultTabs,
+		RequestsPerSecond: DefaultRequestsPerSecond,
+		MaxRetries:        DefaultMaxRetries,
+		RetryBackoff:      DefaultRetryBackoff,
+	}
+}
+
+// A transient failure of a request, which is worth retrying
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// Spaces out requests to Lectio across all tabs
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// Creates a limiter allowing the given amount of requests per second
+func newLimiter(requestsPerSecond float64) *limiter {
+	l := &limiter{}
+	if requestsPerSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	return l
+}
+
+// Waits until the next request slot is available
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleep(ctx, time.Until(slot))
+}
+
+// Navigates the tab of ctx to a Lectio page. The request waits for the limiter, and transient failures such as
+// 5xx responses and the maintenance page are retried with exponential backoff
+func (l *Lectio) navigate(ctx context.Context, url string) error {
+	backoff := l.Options.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := l.limiter.wait(ctx)
+		if err != nil {
+			return err
+		}
+
+		l.logger().Debug("Requesting Lectio page", "url", url, "attempt", attempt+1)
+		err = navigateOnce(ctx, url)
+		var transient *transientError
+		if err == nil || !errors.As(err, &transient) || attempt >= l.Options.MaxRetries {
+			return err
+		}
+
+		l.logger().Warn("Request to Lectio failed, retrying", "url", url, "error", err, "backoff", backoff)
+		err = sleep(ctx, backoff)
+		if err != nil {
+			return err
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+// Navigates the tab of ctx to a Lectio page once, classifying failures as transient where a retry might help
+func navigateOnce(ctx context.Context, url string) error {
+	resp, err := chromedp.RunResponse(ctx, chromedp.Navigate(url))
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		// Network errors (eg. net::ERR_CONNECTION_RESET) are usually transient
+		return &transientError{err}
+	}
+
+	if resp != nil && (resp.Status >= 500 || resp.Status == 429) {
+		return &transientError{fmt.Errorf("Lectio responded with status %d %s", resp.Status, resp.StatusText)}
+	}
+
+	var headings string
+	err = chromedp.Run(ctx, chromedp.Evaluate(pageHeadingsScript, &headings))
+	if err != nil {
+		return err
+	}
+	if strings.Contains(strings.ToLower(headings), maintenanceText) {
+		return &transientError{ErrLectioUnavailable}
+	}
+	return nil
+}
+
+// Runs count jobs concurrently in a pool of l.Options.Tabs browser tabs. If any job fails, the remaining jobs are
+// abandoned and the error of the first failed job is returned
+func (l *Lectio) runInTabs(count int, job func(ctx context.Context, i int) error) error {
+	if count == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(l.Context)
+	defer cancel()
+
+	indices := make(chan int)
+	errs := make(chan error, 1)
+	var wg sync.WaitGroup
+
+	tabs := max(1, min(l.Options.Tabs, count))
+	for t := 0; t < tabs; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Tabs opened from the context of the logged in tab share its cookies
+			tabCtx, closeTab := chromedp.NewContext(ctx)
+			defer closeTab()
+
+			for i := range indices {
+				err := job(tabCtx, i)
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+queue:
+	for i := 0; i < count; i++ {
+		select {
+		case indices <- i:
+		case <-ctx.Done():
+			break queue
+		}
+	}
+	close(indices)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+// Sleeps for the duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,170 @@
+package lectigo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// A persisted Lectio session, ie. the authenticated cookies of a logged in user
+type Session struct {
+	SchoolID string          `json:"schoolID"`
+	Username string          `json:"username"`
+	SavedAt  time.Time       `json:"savedAt"`
+	Cookies  []SessionCookie `json:"cookies"`
+}
+
+// A cookie of a Lectio session
+type SessionCookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires,omitempty"` // Zero for session cookies
+	HTTPOnly bool      `json:"httpOnly"`
+	Secure   bool      `json:"secure"`
+}
+
+// Reads a Lectio session from a session file
+func LoadSession(path string) (*Session, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{}
+	err = json.Unmarshal(b, session)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse session file %q: %w", path, err)
+	}
+	return session, nil
+}
+
+// Writes the session to a session file, which is only readable by the current user
+func (s *Session) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The file might already exist with wider permissions
+	err = f.Chmod(0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(b)
+	return err
+}
+
+// Reports whether the session belongs to the given user at the given school
+func (s *Session) Matches(loginInfo *LectioLoginInfo) bool {
+	return s.SchoolID == loginInfo.SchoolID && strings.EqualFold(s.Username, loginInfo.Username)
+}
+
+// Loads the cookies of the session into the browser
+func (s *Session) apply(ctx context.Context) error {
+	var cookies []*network.CookieParam
+	for _, c := range s.Cookies {
+		if !c.Expires.IsZero() && c.Expires.Before(time.Now()) {
+			continue
+		}
+
+		cookie := &network.CookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+		}
+		if !c.Expires.IsZero() {
+			expires := cdp.TimeSinceEpoch(c.Expires)
+			cookie.Expires = &expires
+		}
+		cookies = append(cookies, cookie)
+	}
+
+	if len(cookies) == 0 {
+		return errors.New("session has no valid cookies")
+	}
+	return chromedp.Run(ctx, network.SetCookies(cookies))
+}
+
+// Reads the Lectio cookies of the browser into a session
+func currentSession(ctx context.Context, loginInfo *LectioLoginInfo) (*Session, error) {
+	var cookies []*network.Cookie
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		cookies, err = network.GetCookies().WithUrls([]string{"https://www.lectio.dk/lectio/"}).Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{
+		SchoolID: loginInfo.SchoolID,
+		Username: loginInfo.Username,
+		SavedAt:  time.Now(),
+	}
+	for _, c := range cookies {
+		cookie := SessionCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+		}
+		if !c.Session {
+			cookie.Expires = time.Unix(int64(c.Expires), 0)
+		}
+		session.Cookies = append(session.Cookies, cookie)
+	}
+	return session, nil
+}
+
+// Reports whether the browser is logged in to Lectio, by checking if the front page redirects to the login page
+func (l *Lectio) isLoggedIn(ctx context.Context) (bool, error) {
+	err := l.navigate(ctx, fmt.Sprintf("https://www.lectio.dk/lectio/%s/forside.aspx", l.LoginInfo.SchoolID))
+	if err != nil {
+		return false, err
+	}
+
+	var location string
+	err = chromedp.Run(ctx,
+		chromedp.WaitReady("body"),
+		chromedp.Location(&location),
+	)
+	if err != nil {
+		return false, err
+	}
+	return !strings.Contains(strings.ToLower(location), "login.aspx"), nil
+}
+
+// Logs in again with the authenticator of the instance if its session has expired, eg. between periodic syncs
+func (l *Lectio) EnsureLoggedIn() error {
+	ok, err := l.isLoggedIn(l.Context)
+	if err != nil {
+		return fmt.Errorf("could not check Lectio session: %w", err)
+	}
+	if ok {
+		return nil
+	}
+	return l.auth.Authenticate(l)
+}
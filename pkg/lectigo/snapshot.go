@@ -0,0 +1,172 @@
+package lectigo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A scraped schedule with the metadata of the scrape, saved to compare schedules over time
+type Snapshot struct {
+	ScrapedAt time.Time         `json:"scrapedAt"`
+	SchoolID  string            `json:"schoolId"`
+	Username  string            `json:"username,omitempty"`
+	Target    ScheduleTarget    `json:"target"`
+	Weeks     int               `json:"weeks"`
+	Modules   map[string]Module `json:"modules"` // The modules in the format of ModulesToJSON
+}
+
+// The differences between two snapshots
+type SnapshotDiff struct {
+	Added   []Module     `json:"added"`
+	Removed []Module     `json:"removed"`
+	Changed []ModuleDiff `json:"changed"`
+}
+
+// The differences between two versions of a module
+type ModuleDiff struct {
+	Id     string      `json:"id"`
+	Old    Module      `json:"old"`
+	New    Module      `json:"new"`
+	Fields []FieldDiff `json:"fields"`
+}
+
+// A field differing between two versions of a module
+type FieldDiff struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Writes the snapshot as indented JSON to path
+func (s *Snapshot) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// Reads a snapshot from path. Files written by ModulesToJSON are read as snapshots without metadata
+func LoadSnapshot(path string) (*Snapshot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{}
+	err = json.Unmarshal(b, snapshot)
+	if err == nil && snapshot.Modules != nil {
+		return snapshot, nil
+	}
+
+	modules := make(map[string]Module)
+	err = json.Unmarshal(b, &modules)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse snapshot %q: %w", path, err)
+	}
+	return &Snapshot{Modules: modules}, nil
+}
+
+// Returns the modules added, removed and changed from the old to the new snapshot, each sorted by start time
+func DiffSnapshots(old, new *Snapshot) *SnapshotDiff {
+	diff := &SnapshotDiff{Added: []Module{}, Removed: []Module{}, Changed: []ModuleDiff{}}
+	for id, newModule := range new.Modules {
+		oldModule, ok := old.Modules[id]
+		if !ok {
+			diff.Added = append(diff.Added, newModule)
+			continue
+		}
+		fields := DiffModuleFields(&oldModule, &newModule)
+		if len(fields) > 0 {
+			diff.Changed = append(diff.Changed, ModuleDiff{Id: id, Old: oldModule, New: newModule, Fields: fields})
+		}
+	}
+	for id, oldModule := range old.Modules {
+		if _, ok := new.Modules[id]; !ok {
+			diff.Removed = append(diff.Removed, oldModule)
+		}
+	}
+
+	sortModules(diff.Added)
+	sortModules(diff.Removed)
+	slices.SortFunc(diff.Changed, func(a, b ModuleDiff) int {
+		if c := a.New.StartDate.Compare(b.New.StartDate); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Id, b.Id)
+	})
+	return diff
+}
+
+// Reports whether the snapshots have no differences
+func (d *SnapshotDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Returns the fields differing between two versions of a module, with their values formatted for display
+func DiffModuleFields(old, new *Module) []FieldDiff {
+	var fields []FieldDiff
+	add := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			fields = append(fields, FieldDiff{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("Mon 2 Jan 2006 15:04")
+	}
+
+	add("title", old.Title, new.Title)
+	add("start", formatTime(old.StartDate), formatTime(new.StartDate))
+	add("end", formatTime(old.EndDate), formatTime(new.EndDate))
+	add("room", old.Location, new.Location)
+	add("teacher", old.Teacher, new.Teacher)
+	add("teams", strings.Join(old.Teams, ", "), strings.Join(new.Teams, ", "))
+	add("group", old.Group, new.Group)
+	add("status", string(old.ModuleStatus), string(new.ModuleStatus))
+	add("homework", old.Homework, new.Homework)
+	add("note", old.Description, new.Description)
+	add("materials", old.Materials, new.Materials)
+	add("attachments", formatAttachments(old.Attachments), formatAttachments(new.Attachments))
+	add("allDay", fmt.Sprint(old.AllDay), fmt.Sprint(new.AllDay))
+	add("holiday", fmt.Sprint(old.Holiday), fmt.Sprint(new.Holiday))
+	add("exam", fmt.Sprint(old.Exam), fmt.Sprint(new.Exam))
+	if !remindersEqual(old.Reminders, new.Reminders) {
+		add("reminders", fmt.Sprint(old.Reminders), fmt.Sprint(new.Reminders))
+	}
+	return fields
+}
+
+func formatAttachments(attachments []Attachment) string {
+	names := make([]string, len(attachments))
+	for i, attachment := range attachments {
+		names[i] = attachment.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Returns the modules sorted by start time
+func SortedModules(modules map[string]Module) []Module {
+	sorted := make([]Module, 0, len(modules))
+	for _, m := range modules {
+		sorted = append(sorted, m)
+	}
+	sortModules(sorted)
+	return sorted
+}
+
+// Sorts the modules by start time, and by ID for modules starting at the same time
+func sortModules(modules []Module) {
+	slices.SortFunc(modules, func(a, b Module) int {
+		if c := a.StartDate.Compare(b.StartDate); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Id, b.Id)
+	})
+}
@@ -0,0 +1,133 @@
+package lectigo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The status of a Lectio module
+type ModuleStatus string
+
+const (
+	StatusNormal    ModuleStatus = ""          // The module takes place as planned
+	StatusChanged   ModuleStatus = "changed"   // The module has been changed ("Ændret!" or "Changed!" in Lectio)
+	StatusCancelled ModuleStatus = "cancelled" // The module has been cancelled ("Aflyst!" or "Cancelled!" in Lectio)
+)
+
+// The Google Calendar colour IDs of the statuses
+const (
+	colorIDCancelled = "4" // Red
+	colorIDChanged   = "2" // Green
+)
+
+// The private extended property of events keeping the status of the module when its colour is set by a transform rule
+const statusProperty = "lectioStatus"
+
+// The status labels of a module tooltip in the Danish and English Lectio UI
+var statusLabels = map[string]ModuleStatus{
+	"ændret!":    StatusChanged,
+	"changed!":   StatusChanged,
+	"aflyst!":    StatusCancelled,
+	"cancelled!": StatusCancelled,
+}
+
+// Parses a module status as shown by Lectio in Danish or English (eg. "Aflyst!" or "Cancelled!"), as well as the
+// values of ModuleStatus itself. The boolean reports whether the text was a status
+func ParseModuleStatus(s string) (ModuleStatus, bool) {
+	if status, ok := statusFromLabel(s); ok {
+		return status, true
+	}
+
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch ModuleStatus(s) {
+	case StatusChanged, StatusCancelled:
+		return ModuleStatus(s), true
+	}
+	return StatusNormal, false
+}
+
+// Parses a module status label of a tooltip (eg. "Aflyst!" or "Cancelled!")
+func statusFromLabel(label string) (ModuleStatus, bool) {
+	status, ok := statusLabels[strings.ToLower(strings.TrimSpace(label))]
+	return status, ok
+}
+
+// Returns a module status from the colour ID of a Google Calendar event
+func StatusFromColorID(colorID string) ModuleStatus {
+	switch colorID {
+	case colorIDCancelled:
+		return StatusCancelled
+	case colorIDChanged:
+		return StatusChanged
+	}
+	return StatusNormal
+}
+
+// Returns the Google Calendar colour ID of the status
+// Cancelled: "4" - red
+// Changed: "2" - green
+// Default "" - default calendar color
+func (s ModuleStatus) ColorID() string {
+	switch s {
+	case StatusCancelled:
+		return colorIDCancelled
+	case StatusChanged:
+		return colorIDChanged
+	}
+	return ""
+}
+
+// Returns the status as shown in the Danish Lectio UI
+func (s ModuleStatus) Danish() string {
+	switch s {
+	case StatusCancelled:
+		return "Aflyst!"
+	case StatusChanged:
+		return "Ændret!"
+	}
+	return ""
+}
+
+// Parses the status from JSON, also accepting the Danish and English labels written by earlier versions
+func (s *ModuleStatus) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*s = StatusNormal
+		return nil
+	}
+	status, ok := ParseModuleStatus(string(text))
+	if !ok {
+		return fmt.Errorf("unknown module status %q", text)
+	}
+	*s = status
+	return nil
+}
+
+// The labels of the elements of a module tooltip in the Danish and English Lectio UI
+var (
+	teacherLabels  = []string{"Lærer: ", "Lærere: ", "Teacher: ", "Teachers: "}
+	roomLabels     = []string{"Lokale: ", "Lokaler: ", "Room: ", "Rooms: "}
+	teamLabels     = []string{"Hold: ", "Team: ", "Teams: "}
+	studentLabels  = []string{"Elever: ", "Students: "}
+	homeworkLabels = []string{"Lektier:", "Homework:"}
+	noteLabels     = []string{"Note:", "Notes:"}
+)
+
+// Reports whether the element starts with one of the labels
+func hasLabel(element string, labels []string) bool {
+	for _, label := range labels {
+		if strings.HasPrefix(element, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether the element is exactly one of the labels
+func isLabel(element string, labels []string) bool {
+	for _, label := range labels {
+		if element == label {
+			return true
+		}
+	}
+	return false
+}
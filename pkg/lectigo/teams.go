@@ -0,0 +1,273 @@
+package lectigo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"golang.org/x/net/html"
+)
+
+// How long a team without a title on its page is left before its page is fetched again
+const teamCacheRetryAge = 30 * 24 * time.Hour
+
+// The prefix of the context card IDs of teams in Lectio, eg. "HE12345678"
+const teamContextCardPrefix = "HE"
+
+// A cache of the titles of teams learned from their pages in Lectio
+type TeamCache struct {
+	SchoolID string                    `json:"schoolId"` // The school the teams belong to
+	Entries  map[string]TeamCacheEntry `json:"entries"`  // Team name to its entry
+
+	mu sync.Mutex
+}
+
+type TeamCacheEntry struct {
+	Title     string    `json:"title"` // The title of the subject of the team. Empty if the page of the team has none
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// The IDs of the teams found in scraped schedules, by team name
+type teamIDs struct {
+	ids map[string]string
+	mu  sync.Mutex
+}
+
+// Reads a team cache from a file. A missing file results in an empty cache
+func LoadTeamCache(path string) (*TeamCache, error) {
+	cache := &TeamCache{Entries: make(map[string]TeamCacheEntry)}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, cache)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse team cache %q: %w", path, err)
+	}
+	if cache.Entries == nil {
+		cache.Entries = make(map[string]TeamCacheEntry)
+	}
+	return cache, nil
+}
+
+// Writes the team cache to a file
+func (c *TeamCache) Save(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// Returns the titles of the teams in the cache
+func (c *TeamCache) Titles() map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	titles := make(map[string]string)
+	for team, entry := range c.Entries {
+		if entry.Title != "" {
+			titles[team] = entry.Title
+		}
+	}
+	return titles
+}
+
+// Reports whether the page of the team needs to be fetched
+func (c *TeamCache) stale(team string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.Entries[team]
+	return !ok || (entry.Title == "" && time.Since(entry.FetchedAt) > teamCacheRetryAge)
+}
+
+func (c *TeamCache) put(team, title string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Entries[team] = TeamCacheEntry{Title: title, FetchedAt: time.Now()}
+}
+
+// Remembers the ID of a team found in a schedule
+func (t *teamIDs) add(team, id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ids == nil {
+		t.ids = make(map[string]string)
+	}
+	t.ids[team] = id
+}
+
+func (t *teamIDs) get(team string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id, ok := t.ids[team]
+	return id, ok
+}
+
+// Collects the IDs of the teams linked in a schedule brick
+func (l *Lectio) collectTeamIDs(n *html.Node) {
+	if card, ok := getAttr(n, "data-lectiocontextcard"); ok && strings.HasPrefix(card, teamContextCardPrefix) {
+		if team := strings.TrimSpace(textContent(n)); team != "" {
+			l.teams.add(team, strings.TrimPrefix(card, teamContextCardPrefix))
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		l.collectTeamIDs(c)
+	}
+}
+
+// Learns the titles of the teams of the modules that the abbreviations can not decode from the pages of the teams in
+// Lectio, and decodes the titles of the modules again. Modules that the blacklist or the filter excludes by their new
+// title are removed. Learned titles are kept in the cache, which may be nil. A cache of another school is emptied
+// first, as teams of the same name may be different subjects at different schools. Only teams found in schedules
+// scraped by l can be learned
+func (l *Lectio) LearnTeamTitles(modules map[string]Module, cache *TeamCache) error {
+	if cache == nil {
+		cache = &TeamCache{Entries: make(map[string]TeamCacheEntry)}
+	}
+	if cache.SchoolID != l.LoginInfo.SchoolID {
+		cache.SchoolID = l.LoginInfo.SchoolID
+		cache.Entries = make(map[string]TeamCacheEntry)
+	}
+	if l.Abbreviations == nil {
+		l.Abbreviations = &Abbreviations{}
+	}
+	if l.Abbreviations.Learned == nil {
+		l.Abbreviations.Learned = make(map[string]string)
+	}
+	maps.Copy(l.Abbreviations.Learned, cache.Titles())
+
+	var toFetch, ids []string
+	for _, module := range modules {
+		for _, team := range module.Teams {
+			if _, ok := l.Abbreviations.Decode(team); ok || slices.Contains(toFetch, team) || !cache.stale(team) {
+				continue
+			}
+			if id, ok := l.teams.get(team); ok {
+				toFetch = append(toFetch, team)
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	l.logger().Debug("Learning team titles", "teams", toFetch)
+	titles := make([]string, len(toFetch))
+	err := l.runInTabs(len(toFetch), func(ctx context.Context, i int) error {
+		title, err := l.getTeamTitle(ctx, ids[i])
+		if err != nil {
+			return fmt.Errorf("could not get page of team %v: %w", toFetch[i], err)
+		}
+		titles[i] = title
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, team := range toFetch {
+		cache.put(team, titles[i])
+		if titles[i] != "" {
+			l.Abbreviations.Learned[team] = titles[i]
+		}
+	}
+
+	for id, module := range modules {
+		if len(module.Teams) == 0 {
+			continue
+		}
+		module.Title = module.rawTitle
+		module.Group = ""
+		l.setModuleTitle(&module)
+		if !l.keepsModule(&module, module.rawTitle) {
+			delete(modules, id)
+			continue
+		}
+		modules[id] = module
+	}
+	return nil
+}
+
+// Fetches the teaching description of a team and returns the title of its subject, or an empty title if the page has
+// none
+func (l *Lectio) getTeamTitle(ctx context.Context, id string) (string, error) {
+	pageUrl := fmt.Sprintf("https://www.lectio.dk/lectio/%s/studieplan/hold_undervisningsbeskrivelse.aspx?holdelementid=%s", l.LoginInfo.SchoolID, url.QueryEscape(id))
+	err := l.navigate(ctx, pageUrl)
+	if err != nil {
+		return "", err
+	}
+
+	var pageHTML string
+	err = chromedp.Run(ctx,
+		chromedp.WaitReady("body"),
+		chromedp.OuterHTML("html", &pageHTML),
+	)
+	if err != nil {
+		return "", err
+	}
+	return parseTeamPage(pageHTML)
+}
+
+// Parses the title of the subject of a team from its teaching description, which has a table row like
+// "Fag og niveau | Matematik A". The level is left out of the title
+func parseTeamPage(pageHTML string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(pageHTML))
+	if err != nil {
+		return "", err
+	}
+
+	var title string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if title != "" {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			var cells []string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.Data == "th" || c.Data == "td") {
+					cells = append(cells, strings.Join(strings.Fields(textContent(c)), " "))
+				}
+			}
+			if len(cells) >= 2 {
+				label := strings.ToLower(strings.TrimSuffix(cells[0], ":"))
+				if label == "fag og niveau" || label == "subject and level" {
+					title = subjectTitle(cells[1])
+				}
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return title, nil
+}
+
+// Returns the title of a subject without its level, eg. "Matematik" for "Matematik A"
+func subjectTitle(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) > 1 && len(fields[len(fields)-1]) == 1 && strings.Contains("ABC", fields[len(fields)-1]) {
+		fields = fields[:len(fields)-1]
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,95 @@
+package lectigo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLearnTeamTitlesFiltersLearnedTitles(t *testing.T) {
+	start := time.Date(2026, 10, 19, 8, 15, 0, 0, time.UTC)
+	modules := map[string]Module{
+		"1": {Id: "1", Title: "2a XY", Teams: []string{"2a XY"}, StartDate: start, EndDate: start.Add(45 * time.Minute)},
+		"2": {Id: "2", Title: "2a MA", Teams: []string{"2a MA"}, StartDate: start, EndDate: start.Add(45 * time.Minute)},
+	}
+	cache := &TeamCache{SchoolID: "123", Entries: map[string]TeamCacheEntry{"2a XY": {Title: "Valgfag", FetchedAt: time.Now()}}}
+
+	rules, err := BlacklistRules([]ClassesToIgnore{{Keywords: []string{"valgfag"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &Lectio{LoginInfo: &LectioLoginInfo{SchoolID: "123"}, Blacklist: rules}
+
+	err = l.LearnTeamTitles(modules, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := modules["1"]; ok {
+		t.Errorf("kept the module blacklisted by its learned title: %+v", modules["1"])
+	}
+	if m, ok := modules["2"]; !ok || m.Title != "Matematik" {
+		t.Errorf("module 2 = %+v, want the decoded module", m)
+	}
+}
+
+func TestDecodeTeam(t *testing.T) {
+	a := &Abbreviations{Subjects: map[string]string{"PSYK": "Psykologi"}}
+	tests := []struct {
+		team  string
+		title string
+		ok    bool
+	}{
+		{"2a MA", "Matematik", true},
+		{"3g FyB", "Fysik", true},
+		{"1x TyF2", "Tysk", true},
+		{"MaA1x", "Matematik", true},
+		{"2a DHO", "Dansk-historieopgave", true},
+		{"3b Psyk", "Psykologi", true},
+		{"Projektuge", "", false},
+		{"Bibliotek", "", false},
+		{"Idrætsdag", "", false},
+		{"1g Biblioteksintro", "", false},
+	}
+	for _, test := range tests {
+		title, ok := a.Decode(test.team)
+		if title != test.title || ok != test.ok {
+			t.Errorf("Decode(%q) = %q, %v, want %q, %v", test.team, title, ok, test.title, test.ok)
+		}
+	}
+}
+
+func TestParseTeam(t *testing.T) {
+	tests := []struct {
+		team string
+		want TeamName
+	}{
+		{"2a MA", TeamName{Class: "2a", Subject: "MA"}},
+		{"3g FyB", TeamName{Class: "3g", Subject: "FY", Level: "B"}},
+		{"1x TyF2", TeamName{Class: "1x", Subject: "TY", Suffix: "F2"}},
+		{"MaA1x", TeamName{Subject: "MA", Level: "A", Suffix: "1X"}},
+		{"Projektuge", TeamName{Subject: "PROJEKTUGE"}},
+		{"Idrætsdag", TeamName{Subject: "IDRÆTSDAG"}},
+	}
+	for _, test := range tests {
+		if got := (*Abbreviations)(nil).ParseTeam(test.team); got != test.want {
+			t.Errorf("ParseTeam(%q) = %+v, want %+v", test.team, got, test.want)
+		}
+	}
+}
+
+func TestLearnTeamTitlesDiscardsOtherSchool(t *testing.T) {
+	modules := map[string]Module{"1": {Id: "1", Title: "2a XY", Teams: []string{"2a XY"}}}
+	cache := &TeamCache{SchoolID: "456", Entries: map[string]TeamCacheEntry{"2a XY": {Title: "Valgfag", FetchedAt: time.Now()}}}
+	l := &Lectio{LoginInfo: &LectioLoginInfo{SchoolID: "123"}}
+
+	// The team was not found in a scraped schedule, so it is not fetched
+	err := l.LearnTeamTitles(modules, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title := modules["1"].Title; title != "2a XY" {
+		t.Errorf("title = %q, want the title of the other school to be discarded", title)
+	}
+	if cache.SchoolID != "123" || len(cache.Entries) != 0 {
+		t.Errorf("cache = %+v, want an empty cache of school 123", cache)
+	}
+}
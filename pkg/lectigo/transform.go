@@ -0,0 +1,199 @@
+package lectigo
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A list of rules changing how modules are shown in the calendar. Every rule matching a module is applied, in order
+type Transform struct {
+	Rules []TransformRule
+}
+
+// A rule changing the modules matching its conditions.
+//
+// The title and appended description are templates, where placeholders like {title} or {room} are replaced with the
+// values of the module: title, group, team, teacher, room, date, start, end and status
+type TransformRule struct {
+	Name              string     `yaml:"name"`              // Identifies the rule in errors
+	Match             Conditions `yaml:"match"`             // The modules the rule changes. Without conditions, every module is changed
+	Title             string     `yaml:"title"`             // Replaces the title, eg. "Matematik" or "{title} ({room})"
+	Color             string     `yaml:"color"`             // The colour of the event, as a name (eg. tomato or sage) or a Google Calendar colour ID
+	AddReminders      []int      `yaml:"addReminders"`      // Reminders added in minutes before the start of the module. Replaces the calendar default
+	Visibility        string     `yaml:"visibility"`        // The visibility of the event: default, public, private or confidential
+	AppendDescription string     `yaml:"appendDescription"` // Text added to the end of the description
+
+	colorID string
+}
+
+// The names of the Google Calendar event colours as shown in the Google Calendar UI. The colour ID is the position
+// in the list, starting from 1
+var colorNames = []string{"lavender", "sage", "grape", "flamingo", "banana", "tangerine", "peacock", "graphite", "blueberry", "basil", "tomato"}
+
+// The visibilities of Google Calendar events
+var visibilities = []string{"default", "public", "private", "confidential"}
+
+// Placeholders of title and description templates, eg. {room}
+var rePlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// The values of the placeholders of templates
+var placeholders = map[string]func(m *Module) string{
+	"title":   func(m *Module) string { return m.Title },
+	"group":   func(m *Module) string { return m.Group },
+	"team":    func(m *Module) string { return strings.Join(m.Teams, ", ") },
+	"teacher": func(m *Module) string { return m.TeacherInitials() },
+	"room":    func(m *Module) string { return m.RoomNames() },
+	"date":    func(m *Module) string { return m.StartDate.Format(time.DateOnly) },
+	"start":   func(m *Module) string { return m.StartDate.Format("15:04") },
+	"end":     func(m *Module) string { return m.EndDate.Format("15:04") },
+	"status":  func(m *Module) string { return string(m.ModuleStatus) },
+}
+
+// Parses a YAML list of transform rules
+func ParseTransform(b []byte) (*Transform, error) {
+	transform := &Transform{}
+	err := yaml.Unmarshal(b, &transform.Rules)
+	if err != nil {
+		return nil, err
+	}
+	for i := range transform.Rules {
+		err = transform.Rules[i].compile()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", transform.Rules[i].DisplayName(i), err)
+		}
+	}
+	return transform, nil
+}
+
+// Reads a YAML list of transform rules from a file
+func LoadTransform(path string) (*Transform, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	transform, err := ParseTransform(b)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse transforms file %q: %w", path, err)
+	}
+	return transform, nil
+}
+
+// Applies every rule matching the module to it. A nil transform leaves the module unchanged
+func (t *Transform) Apply(m *Module) {
+	if t == nil {
+		return
+	}
+	for i := range t.Rules {
+		if t.Rules[i].Match.Matches(m, "") {
+			t.Rules[i].apply(m)
+		}
+	}
+}
+
+// Returns a copy of the modules with the transform applied to each of them. A nil transform returns the modules
+func (t *Transform) ApplyAll(modules map[string]Module) map[string]Module {
+	if t == nil {
+		return modules
+	}
+	transformed := make(map[string]Module, len(modules))
+	for id, m := range modules {
+		m.Reminders = slices.Clone(m.Reminders)
+		t.Apply(&m)
+		transformed[id] = m
+	}
+	return transformed
+}
+
+// Returns the name of the rule, or a name from its position in the transform if it has none
+func (r *TransformRule) DisplayName(i int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("rule %v", i+1)
+}
+
+func (r *TransformRule) apply(m *Module) {
+	if r.Title != "" {
+		m.Title = expandTemplate(r.Title, m)
+	}
+	if r.colorID != "" {
+		m.Color = r.colorID
+	}
+	for _, minutes := range r.AddReminders {
+		if !slices.Contains(m.Reminders, minutes) {
+			m.Reminders = append(m.Reminders, minutes)
+		}
+	}
+	switch r.Visibility {
+	case "":
+	case "default":
+		m.Visibility = ""
+	default:
+		m.Visibility = r.Visibility
+	}
+	if r.AppendDescription != "" {
+		description := expandTemplate(r.AppendDescription, m)
+		if m.Description != "" {
+			description = m.Description + "\n" + description
+		}
+		m.Description = description
+	}
+}
+
+// Checks the changes of the rule and parses its conditions
+func (r *TransformRule) compile() error {
+	err := r.Match.compile()
+	if err != nil {
+		return err
+	}
+
+	r.colorID = ""
+	if r.Color != "" {
+		color := strings.ToLower(strings.TrimSpace(r.Color))
+		if i := slices.Index(colorNames, color); i >= 0 {
+			r.colorID = strconv.Itoa(i + 1)
+		} else if n, err := strconv.Atoi(color); err == nil && n >= 1 && n <= len(colorNames) {
+			r.colorID = strconv.Itoa(n)
+		} else {
+			return fmt.Errorf("unknown colour %q (use a colour ID from 1 to %v or one of %s)", r.Color, len(colorNames), strings.Join(colorNames, ", "))
+		}
+	}
+
+	r.Visibility = strings.ToLower(strings.TrimSpace(r.Visibility))
+	if r.Visibility != "" && !slices.Contains(visibilities, r.Visibility) {
+		return fmt.Errorf("unknown visibility %q (use %s)", r.Visibility, strings.Join(visibilities, ", "))
+	}
+
+	for _, minutes := range r.AddReminders {
+		if minutes < 0 {
+			return fmt.Errorf("invalid reminder %v (use minutes before the start of the module)", minutes)
+		}
+	}
+
+	for _, template := range []string{r.Title, r.AppendDescription} {
+		for _, match := range rePlaceholder.FindAllStringSubmatch(template, -1) {
+			if _, ok := placeholders[match[1]]; !ok {
+				return fmt.Errorf("unknown placeholder %q in %q", match[0], template)
+			}
+		}
+	}
+	return nil
+}
+
+// Replaces the placeholders of the template with the values of the module
+func expandTemplate(template string, m *Module) string {
+	return rePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, ok := placeholders[placeholder[1:len(placeholder)-1]]
+		if !ok {
+			return placeholder
+		}
+		return value(m)
+	})
+}
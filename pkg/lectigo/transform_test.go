@@ -0,0 +1,137 @@
+package lectigo
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseTransform(t *testing.T) {
+	tests := []struct {
+		rule       string
+		colorID    string
+		visibility string
+		valid      bool
+	}{
+		{"color: tomato", "11", "", true},
+		{"color: Lavender", "1", "", true},
+		{"color: 7", "7", "", true},
+		{`color: "11"`, "11", "", true},
+		{"color: 0", "", "", false},
+		{"color: 12", "", "", false},
+		{"color: pink", "", "", false},
+		{"visibility: Private", "", "private", true},
+		{"visibility: default", "", "default", true},
+		{"visibility: secret", "", "", false},
+		{"addReminders: [10, 0]", "", "", true},
+		{"addReminders: [-5]", "", "", false},
+		{`title: "{title} ({room})"`, "", "", true},
+		{`title: "{subject}"`, "", "", false},
+		{`appendDescription: "Lokale {rooms}"`, "", "", false},
+		{"match: {time: late}", "", "", false},
+	}
+	for _, test := range tests {
+		transform, err := ParseTransform([]byte("- " + test.rule))
+		if !test.valid {
+			if err == nil {
+				t.Errorf("ParseTransform(%q) succeeded", test.rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseTransform(%q): %v", test.rule, err)
+			continue
+		}
+		rule := transform.Rules[0]
+		if rule.colorID != test.colorID || rule.Visibility != test.visibility {
+			t.Errorf("%q: colour ID and visibility = %q, %q, want %q, %q", test.rule, rule.colorID, rule.Visibility, test.colorID, test.visibility)
+		}
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	start := time.Date(2026, 10, 19, 8, 15, 0, 0, time.UTC)
+	m := &Module{
+		Title:        "Matematik",
+		Group:        "Matematik A",
+		Teams:        []string{"3a MA", "3b MA"},
+		Teachers:     []Teacher{{Initials: "ABC"}, {Initials: "DEF"}},
+		Rooms:        []string{"1.23", "2.01"},
+		StartDate:    start,
+		EndDate:      start.Add(45 * time.Minute),
+		ModuleStatus: StatusChanged,
+	}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{title} ({room})", "Matematik (1.23, 2.01)"},
+		{"{group}: {team}", "Matematik A: 3a MA, 3b MA"},
+		{"{teacher}", "ABC, DEF"},
+		{"{date} {start}-{end}", "2026-10-19 08:15-09:00"},
+		{"{status}", string(StatusChanged)},
+		{"no placeholders", "no placeholders"},
+		{"{unknown} and {} stay", "{unknown} and {} stay"},
+	}
+	for _, test := range tests {
+		if got := expandTemplate(test.template, m); got != test.want {
+			t.Errorf("expandTemplate(%q) = %q, want %q", test.template, got, test.want)
+		}
+	}
+}
+
+func TestTransformApplyAll(t *testing.T) {
+	transform, err := ParseTransform([]byte(`
+- match:
+    title: matematik
+  title: "{title} ({room})"
+  color: sage
+  addReminders: [10]
+- match:
+    time: "-09:00"
+  addReminders: [10, 30]
+  visibility: private
+  appendDescription: "Tidligt: {start}"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 10, 19, 8, 15, 0, 0, time.UTC)
+	reminders := make([]int, 1, 4)
+	reminders[0] = 60
+	modules := map[string]Module{
+		"1": {Id: "1", Title: "Matematik", Location: "1.23", StartDate: start, EndDate: start.Add(45 * time.Minute), Reminders: reminders, Description: "ABC"},
+		"2": {Id: "2", Title: "Fysik", Location: "2.01", StartDate: start.Add(2 * time.Hour), EndDate: start.Add(3 * time.Hour)},
+	}
+
+	transformed := transform.ApplyAll(modules)
+
+	maths := transformed["1"]
+	if maths.Title != "Matematik (1.23)" || maths.Color != "2" || maths.Visibility != "private" {
+		t.Errorf("maths = %+v", maths)
+	}
+	if !slices.Equal(maths.Reminders, []int{60, 10, 30}) {
+		t.Errorf("reminders = %v, want [60 10 30] without duplicates", maths.Reminders)
+	}
+	if maths.Description != "ABC\nTidligt: 08:15" {
+		t.Errorf("description = %q", maths.Description)
+	}
+	if fysik := transformed["2"]; fysik.Title != "Fysik" || fysik.Color != "" || fysik.Reminders != nil {
+		t.Errorf("fysik = %+v, want it unchanged", fysik)
+	}
+
+	// The modules given are left unchanged, including the array behind their reminders
+	if original := modules["1"]; original.Title != "Matematik" || original.Color != "" || original.Description != "ABC" {
+		t.Errorf("the original module changed: %+v", original)
+	}
+	if reminders[:cap(reminders)][1] != 0 || len(modules["1"].Reminders) != 1 {
+		t.Errorf("the reminders of the original module changed: %v", reminders[:cap(reminders)])
+	}
+
+	var none *Transform
+	if got := none.ApplyAll(modules); got["1"].Title != "Matematik" || len(got) != 2 {
+		t.Errorf("a nil transform changed the modules: %+v", got)
+	}
+}
@@ -0,0 +1,224 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+)
+
+// Posts the changes as JSON to a URL
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string // Extra headers, eg. for authorization
+	Client  *http.Client
+}
+
+// The JSON body posted by WebhookNotifier
+type webhookPayload struct {
+	Account string          `json:"account,omitempty"`
+	Title   string          `json:"title"`
+	Changes []webhookChange `json:"changes"`
+}
+
+type webhookChange struct {
+	Type            lectigo.ChangeType `json:"type"`
+	Summary         string             `json:"summary"`
+	ModuleID        string             `json:"moduleId"`
+	Title           string             `json:"title"`
+	Start           time.Time          `json:"start"`
+	End             time.Time          `json:"end"`
+	Room            string             `json:"room,omitempty"`
+	Teacher         string             `json:"teacher,omitempty"`
+	Homework        string             `json:"homework,omitempty"`
+	PreviousStart   *time.Time         `json:"previousStart,omitempty"`
+	PreviousEnd     *time.Time         `json:"previousEnd,omitempty"`
+	PreviousRoom    string             `json:"previousRoom,omitempty"`
+	PreviousTeacher string             `json:"previousTeacher,omitempty"`
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, account string, changes []lectigo.Change) error {
+	payload := webhookPayload{
+		Account: account,
+		Title:   title(account, changes),
+		Changes: make([]webhookChange, len(changes)),
+	}
+	for i, change := range changes {
+		m := change.Module
+		payload.Changes[i] = webhookChange{
+			Type:     change.Type,
+			Summary:  change.String(),
+			ModuleID: m.Id,
+			Title:    m.Title,
+			Start:    m.StartDate,
+			End:      m.EndDate,
+			Room:     m.Location,
+			Teacher:  m.Teacher,
+			Homework: m.Homework,
+		}
+		if p := change.Previous; p != nil {
+			payload.Changes[i].PreviousStart = &p.StartDate
+			payload.Changes[i].PreviousEnd = &p.EndDate
+			payload.Changes[i].PreviousRoom = p.Location
+			payload.Changes[i].PreviousTeacher = p.Teacher
+		}
+	}
+	return postJSON(ctx, n.Client, n.URL, n.Headers, payload)
+}
+
+// Publishes the changes to a topic of an ntfy server
+type NtfyNotifier struct {
+	Server   string // Defaults to https://ntfy.sh
+	Topic    string
+	Token    string // Access token of protected topics
+	Priority string // Priority of the message, eg. "high"
+	Client   *http.Client
+}
+
+// The server used by NtfyNotifier when it has none
+const DefaultNtfyServer = "https://ntfy.sh"
+
+func (n *NtfyNotifier) Notify(ctx context.Context, account string, changes []lectigo.Change) error {
+	server := n.Server
+	if server == "" {
+		server = DefaultNtfyServer
+	}
+	url := strings.TrimSuffix(server, "/") + "/" + n.Topic
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(text(changes)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", mime.QEncoding.Encode("utf-8", title(account, changes)))
+	req.Header.Set("Tags", "calendar")
+	if n.Priority != "" {
+		req.Header.Set("Priority", n.Priority)
+	}
+	if n.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+	return do(n.Client, req)
+}
+
+// The dialect of a chat webhook
+type ChatKind string
+
+const (
+	ChatDiscord ChatKind = "discord"
+	ChatSlack   ChatKind = "slack"
+)
+
+// Posts the changes as a message to a Discord or Slack compatible incoming webhook
+type ChatNotifier struct {
+	Kind   ChatKind
+	URL    string
+	Client *http.Client
+}
+
+func (n *ChatNotifier) Notify(ctx context.Context, account string, changes []lectigo.Change) error {
+	message := fmt.Sprintf("**%s**\n%s", title(account, changes), text(changes))
+
+	var payload any
+	switch n.Kind {
+	case ChatDiscord:
+		// Discord rejects messages longer than 2000 characters
+		if len([]rune(message)) > 2000 {
+			message = string([]rune(message)[:1997]) + "..."
+		}
+		payload = map[string]string{"content": message}
+	case ChatSlack:
+		// Slack marks bold text with single asterisks
+		payload = map[string]string{"text": strings.Replace(message, "**", "*", 2)}
+	default:
+		return fmt.Errorf("unknown chat kind %q", n.Kind)
+	}
+	return postJSON(ctx, n.Client, n.URL, nil, payload)
+}
+
+// Sends the changes as an email through an SMTP server
+type SMTPNotifier struct {
+	Host     string
+	Port     int // Defaults to 587
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, account string, changes []lectigo.Change) error {
+	port := n.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(n.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", title(account, changes)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(text(changes), "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	// smtp.SendMail has no context, so it is run aside and abandoned on cancellation
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, n.From, n.To, msg.Bytes())
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Posts the payload as JSON
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload any) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return do(client, req)
+}
+
+// Sends the request with the client, or the default client if nil
+func do(client *http.Client, req *http.Request) error {
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp)
+}
@@ -0,0 +1,346 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+)
+
+var (
+	testStart  = time.Date(2026, 10, 19, 8, 15, 0, 0, time.UTC)
+	testModule = lectigo.Module{
+		Id:        "61234567890",
+		Title:     "Matematik",
+		StartDate: testStart,
+		EndDate:   testStart.Add(45 * time.Minute),
+		Location:  "1.23",
+		Teacher:   "ABC",
+		Homework:  "Side 12-14",
+	}
+	testChanges = []lectigo.Change{
+		{Type: lectigo.ChangeRoom, Module: testModule, Previous: &lectigo.Module{StartDate: testStart, EndDate: testStart.Add(45 * time.Minute), Location: "2.01", Teacher: "ABC"}},
+	}
+)
+
+// A request received by a test server
+type received struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// Starts a server recording the requests it receives
+func newRecordingServer(t *testing.T) (*httptest.Server, chan received) {
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{path: r.URL.Path, header: r.Header, body: body}
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	n := &WebhookNotifier{URL: server.URL + "/hook", Headers: map[string]string{"X-Secret": "abc"}}
+
+	err := n.Notify(context.Background(), "anna", testChanges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := <-requests
+
+	if r.path != "/hook" {
+		t.Errorf("path = %q, want /hook", r.path)
+	}
+	if got := r.header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	if got := r.header.Get("X-Secret"); got != "abc" {
+		t.Errorf("X-Secret = %q, want abc", got)
+	}
+
+	var payload webhookPayload
+	err = json.Unmarshal(r.body, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.Account != "anna" || payload.Title != "1 Lectio change for anna" {
+		t.Errorf("account and title = %q, %q", payload.Account, payload.Title)
+	}
+	if len(payload.Changes) != 1 {
+		t.Fatalf("got %v changes, want 1", len(payload.Changes))
+	}
+	c := payload.Changes[0]
+	if c.Type != lectigo.ChangeRoom || c.ModuleID != testModule.Id || c.Room != "1.23" || c.PreviousRoom != "2.01" {
+		t.Errorf("change = %+v", c)
+	}
+	if !c.Start.Equal(testStart) || c.PreviousStart == nil || !c.PreviousStart.Equal(testStart) {
+		t.Errorf("start = %v, previous start = %v", c.Start, c.PreviousStart)
+	}
+}
+
+func TestNtfyNotifier(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	n := &NtfyNotifier{Server: server.URL + "/", Topic: "lectio", Token: "tk_secret", Priority: "high"}
+
+	err := n.Notify(context.Background(), "", testChanges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := <-requests
+
+	if r.path != "/lectio" {
+		t.Errorf("path = %q, want /lectio", r.path)
+	}
+	title, err := new(mime.WordDecoder).DecodeHeader(r.header.Get("Title"))
+	if err != nil || title != "1 Lectio change" {
+		t.Errorf("Title = %q (%v), want 1 Lectio change", title, err)
+	}
+	for header, want := range map[string]string{"Authorization": "Bearer tk_secret", "Priority": "high", "Tags": "calendar"} {
+		if got := r.header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if string(r.body) != testChanges[0].String() {
+		t.Errorf("body = %q, want %q", r.body, testChanges[0].String())
+	}
+}
+
+func TestNtfyNotifierWithoutToken(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	n := &NtfyNotifier{Server: server.URL, Topic: "lectio"}
+
+	err := n.Notify(context.Background(), "", testChanges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := <-requests
+	if got := r.header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q, want none", got)
+	}
+}
+
+func TestChatNotifierDiscordTruncates(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	n := &ChatNotifier{Kind: ChatDiscord, URL: server.URL}
+
+	changes := make([]lectigo.Change, 100)
+	for i := range changes {
+		changes[i] = testChanges[0]
+	}
+	err := n.Notify(context.Background(), "", changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := <-requests
+
+	var payload map[string]string
+	err = json.Unmarshal(r.body, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := payload["content"]
+	if length := len([]rune(content)); length != 2000 {
+		t.Errorf("content has %v characters, want 2000", length)
+	}
+	if !strings.HasPrefix(content, "**100 Lectio changes**\n") || !strings.HasSuffix(content, "...") {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestChatNotifierDiscordShort(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	n := &ChatNotifier{Kind: ChatDiscord, URL: server.URL}
+
+	err := n.Notify(context.Background(), "", testChanges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := <-requests
+
+	var payload map[string]string
+	json.Unmarshal(r.body, &payload)
+	want := "**1 Lectio change**\n" + testChanges[0].String()
+	if payload["content"] != want {
+		t.Errorf("content = %q, want %q", payload["content"], want)
+	}
+}
+
+func TestChatNotifierSlackBold(t *testing.T) {
+	server, requests := newRecordingServer(t)
+	n := &ChatNotifier{Kind: ChatSlack, URL: server.URL}
+
+	err := n.Notify(context.Background(), "anna", testChanges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := <-requests
+
+	var payload map[string]string
+	err = json.Unmarshal(r.body, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "*1 Lectio change for anna*\n" + testChanges[0].String()
+	if payload["text"] != want {
+		t.Errorf("text = %q, want %q", payload["text"], want)
+	}
+}
+
+func TestChatNotifierFailingResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+	n := &ChatNotifier{Kind: ChatSlack, URL: server.URL}
+
+	err := n.Notify(context.Background(), "", testChanges)
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("err = %v, want a 400 response error", err)
+	}
+}
+
+// Starts a fake SMTP server accepting a single message, and returns its port and a channel receiving the envelope
+// and data of the message
+func newFakeSMTPServer(t *testing.T) (int, chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+		reply("220 localhost ESMTP")
+
+		var envelope []string
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM"), strings.HasPrefix(command, "RCPT TO"):
+				envelope = append(envelope, strings.TrimSpace(line))
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 Bye")
+				messages <- append(envelope, data.String())
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, messages
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	port, messages := newFakeSMTPServer(t)
+	n := &SMTPNotifier{Host: "127.0.0.1", Port: port, From: "lectigo@example.com", To: []string{"anna@example.com"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := n.Notify(ctx, "anna", testChanges)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var message []string
+	select {
+	case message = <-messages:
+	case <-ctx.Done():
+		t.Fatal("the fake SMTP server received no message")
+	}
+	if len(message) != 3 {
+		t.Fatalf("message = %q", message)
+	}
+	if message[0] != "MAIL FROM:<lectigo@example.com>" || message[1] != "RCPT TO:<anna@example.com>" {
+		t.Errorf("envelope = %q", message[:2])
+	}
+	data := message[2]
+	for _, want := range []string{
+		"From: lectigo@example.com\r\n",
+		"To: anna@example.com\r\n",
+		"Subject: " + mime.QEncoding.Encode("utf-8", "1 Lectio change for anna") + "\r\n",
+		"Content-Type: text/plain; charset=UTF-8\r\n",
+		"\r\n\r\n" + testChanges[0].String() + "\r\n",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("data %q does not contain %q", data, want)
+		}
+	}
+}
+
+func TestSMTPNotifierUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	n := &SMTPNotifier{Host: "127.0.0.1", Port: port, From: "lectigo@example.com", To: []string{"anna@example.com"}}
+	err = n.Notify(context.Background(), "", testChanges)
+	if err == nil {
+		t.Errorf("sending to closed port %v succeeded", port)
+	}
+}
+
+func TestDispatcherErrorHidesURL(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	const token = "webhook-secret-token"
+	d := &Dispatcher{Channels: []Channel{{Name: "discord", Notifier: &ChatNotifier{Kind: ChatDiscord, URL: "http://" + addr + "/api/webhooks/1/" + token}}}}
+	err = d.Notify(context.Background(), "", testChanges)
+	if err == nil {
+		t.Fatal("notifying a closed port succeeded")
+	}
+	if strings.Contains(err.Error(), token) {
+		t.Errorf("error %q contains the webhook token", err)
+	}
+	if !strings.Contains(err.Error(), "discord") {
+		t.Errorf("error %q does not name the channel", err)
+	}
+}
@@ -0,0 +1,251 @@
+// Package notify sends the changes noticed by a sync through notification channels like webhooks, ntfy, chat
+// webhooks and email
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+)
+
+// Sends notifications about changes through a single channel
+type Notifier interface {
+	// Sends a notification about the changes of the account. The changes are never empty
+	Notify(ctx context.Context, account string, changes []lectigo.Change) error
+}
+
+// A notifier only sending the changes that pass its filter
+type Channel struct {
+	Name     string // Identifies the channel in errors
+	Notifier Notifier
+	Filter   Filter
+}
+
+// Decides which changes are sent through a channel
+type Filter struct {
+	Types      []lectigo.ChangeType // The change types to send. Empty for all
+	QuietHours *QuietHours          // Changes within the quiet hours are held back until they end. Nil for none
+}
+
+// A daily period in which no notifications are sent, eg. 22:00-07:00
+type QuietHours struct {
+	Start time.Duration // Time of day the quiet hours start
+	End   time.Duration // Time of day the quiet hours end. Before Start if they cross midnight
+}
+
+// Sends notifications through several channels
+type Dispatcher struct {
+	Channels    []Channel
+	Timeout     time.Duration // The timeout of each channel. Zero for DefaultTimeout
+	PendingPath string        // The file queuing the changes held back by quiet hours. Empty to drop them instead
+
+	mu sync.Mutex // Serialises access to the pending file, which is shared by the accounts
+}
+
+// The timeout of a channel when Dispatcher has none
+const DefaultTimeout = 30 * time.Second
+
+// The HTTP client used by the notifiers when they have none
+var defaultClient = &http.Client{Timeout: DefaultTimeout}
+
+// Sends the changes of the account through every channel whose filter lets any of them through. Changes within the
+// quiet hours of a channel are queued, and sent by the first call after the quiet hours, even if it has no changes of
+// its own. The changes of a channel failing to send are queued again. All channels are tried even if some fail
+func (d *Dispatcher) Notify(ctx context.Context, account string, changes []lectigo.Change) error {
+	return d.notify(ctx, account, changes, time.Now())
+}
+
+// Sends the changes of the account as Notify does at the given time
+func (d *Dispatcher) notify(ctx context.Context, account string, changes []lectigo.Change, now time.Time) error {
+	timeout := d.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	batches, err := d.batch(account, changes, now)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	failed := make(map[string][]lectigo.Change)
+	for i, channel := range d.Channels {
+		if len(batches[i]) == 0 {
+			continue
+		}
+
+		channelCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := channel.Notifier.Notify(channelCtx, account, batches[i])
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not notify through %s: %w", channel.Name, stripURL(err)))
+			failed[channel.Name] = batches[i]
+		}
+	}
+
+	err = d.requeue(account, failed)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Returns the changes of the account to send through each channel at the given time. The changes of channels within
+// their quiet hours are queued in the pending file, and the queued changes of the other channels are taken from it
+func (d *Dispatcher) batch(account string, changes []lectigo.Change, now time.Time) ([][]lectigo.Change, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	pending := make(pendingChanges)
+	if d.PendingPath != "" {
+		var err error
+		pending, err = loadPending(d.PendingPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not load pending notifications: %w", err)
+		}
+	}
+
+	modified := false
+	batches := make([][]lectigo.Change, len(d.Channels))
+	for i, channel := range d.Channels {
+		filtered := channel.Filter.Apply(changes)
+		if channel.Filter.QuietHours != nil && channel.Filter.QuietHours.Contains(now) {
+			if d.PendingPath != "" && len(filtered) > 0 {
+				pending.add(channel.Name, account, filtered)
+				modified = true
+			}
+			continue
+		}
+
+		queued := pending.take(channel.Name, account)
+		modified = modified || len(queued) > 0
+		batches[i] = append(queued, filtered...)
+	}
+
+	if modified {
+		err := pending.save(d.PendingPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not save pending notifications: %w", err)
+		}
+	}
+	return batches, nil
+}
+
+// Queues the changes of the account that could not be sent again, by channel name, so the next call sends them
+func (d *Dispatcher) requeue(account string, failed map[string][]lectigo.Change) error {
+	if d.PendingPath == "" || len(failed) == 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	pending, err := loadPending(d.PendingPath)
+	if err != nil {
+		return fmt.Errorf("could not load pending notifications: %w", err)
+	}
+	for channel, changes := range failed {
+		pending.add(channel, account, changes)
+	}
+	err = pending.save(d.PendingPath)
+	if err != nil {
+		return fmt.Errorf("could not save pending notifications: %w", err)
+	}
+	return nil
+}
+
+// Returns the changes of the types of the filter
+func (f *Filter) Apply(changes []lectigo.Change) []lectigo.Change {
+	if len(f.Types) == 0 {
+		return changes
+	}
+
+	var filtered []lectigo.Change
+	for _, change := range changes {
+		if slices.Contains(f.Types, change.Type) {
+			filtered = append(filtered, change)
+		}
+	}
+	return filtered
+}
+
+// Parses quiet hours like "22:00-07:00"
+func ParseQuietHours(s string) (*QuietHours, error) {
+	startValue, endValue, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("quiet hours %q must be like 22:00-07:00", s)
+	}
+
+	start, err := parseTimeOfDay(startValue)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTimeOfDay(endValue)
+	if err != nil {
+		return nil, err
+	}
+	return &QuietHours{Start: start, End: end}, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Reports whether t is within the quiet hours
+func (q *QuietHours) Contains(t time.Time) bool {
+	timeOfDay := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if q.Start <= q.End {
+		return timeOfDay >= q.Start && timeOfDay < q.End
+	}
+	return timeOfDay >= q.Start || timeOfDay < q.End
+}
+
+// Returns the title of a notification about the changes
+func title(account string, changes []lectigo.Change) string {
+	noun := "changes"
+	if len(changes) == 1 {
+		noun = "change"
+	}
+	if account == "" {
+		return fmt.Sprintf("%v Lectio %s", len(changes), noun)
+	}
+	return fmt.Sprintf("%v Lectio %s for %s", len(changes), noun, account)
+}
+
+// Returns the changes as text with a line per change
+func text(changes []lectigo.Change) string {
+	lines := make([]string, len(changes))
+	for i, change := range changes {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Removes the URL from errors of HTTP requests, as the URLs of chat webhooks hold their secret token
+func stripURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s request failed: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// Returns an error for HTTP responses other than 2xx
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return nil
+}
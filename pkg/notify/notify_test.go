@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+)
+
+func TestQuietHoursContains(t *testing.T) {
+	tests := []struct {
+		quietHours string
+		time       string
+		want       bool
+	}{
+		{"22:00-07:00", "21:59", false},
+		{"22:00-07:00", "22:00", true},
+		{"22:00-07:00", "23:30", true},
+		{"22:00-07:00", "00:00", true},
+		{"22:00-07:00", "06:30", true},
+		{"22:00-07:00", "06:59", true},
+		{"22:00-07:00", "07:00", false},
+		{"22:00-07:00", "12:00", false},
+		{"08:00-16:00", "07:59", false},
+		{"08:00-16:00", "08:00", true},
+		{"08:00-16:00", "15:59", true},
+		{"08:00-16:00", "16:00", false},
+		{"00:00-00:00", "12:00", false},
+	}
+	for _, test := range tests {
+		q, err := ParseQuietHours(test.quietHours)
+		if err != nil {
+			t.Fatal(err)
+		}
+		at, err := time.Parse("15:04", test.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		at = time.Date(2026, 10, 19, at.Hour(), at.Minute(), 0, 0, time.Local)
+		if got := q.Contains(at); got != test.want {
+			t.Errorf("%s contains %s = %v, want %v", test.quietHours, test.time, got, test.want)
+		}
+	}
+}
+
+func TestParseQuietHoursInvalid(t *testing.T) {
+	for _, s := range []string{"", "22:00", "22-07", "25:00-07:00", "22:00-7"} {
+		_, err := ParseQuietHours(s)
+		if err == nil {
+			t.Errorf("ParseQuietHours(%q) succeeded", s)
+		}
+	}
+}
+
+func TestFilterApply(t *testing.T) {
+	changes := []lectigo.Change{{Type: lectigo.ChangeNew}, {Type: lectigo.ChangeCancelled}, {Type: lectigo.ChangeRoom}}
+
+	all := (&Filter{}).Apply(changes)
+	if len(all) != 3 {
+		t.Errorf("a filter without types kept %v changes, want 3", len(all))
+	}
+
+	filtered := (&Filter{Types: []lectigo.ChangeType{lectigo.ChangeCancelled, lectigo.ChangeRoom}}).Apply(changes)
+	if len(filtered) != 2 || filtered[0].Type != lectigo.ChangeCancelled || filtered[1].Type != lectigo.ChangeRoom {
+		t.Errorf("filtered = %+v", filtered)
+	}
+}
+
+// Records the changes it is asked to send, and fails while err is set
+type recordingNotifier struct {
+	sent [][]lectigo.Change
+	err  error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, account string, changes []lectigo.Change) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, changes)
+	return nil
+}
+
+var (
+	quietHours  = &QuietHours{Start: 22 * time.Hour, End: 7 * time.Hour}
+	duringQuiet = time.Date(2026, 10, 19, 23, 0, 0, 0, time.Local)
+	afterQuiet  = time.Date(2026, 10, 20, 8, 0, 0, 0, time.Local)
+)
+
+// Returns a dispatcher with a single channel with quiet hours from 22:00 to 07:00 sending through the notifier
+func newQuietDispatcher(t *testing.T, notifier Notifier) *Dispatcher {
+	return &Dispatcher{
+		Channels:    []Channel{{Name: "phone", Notifier: notifier, Filter: Filter{QuietHours: quietHours}}},
+		PendingPath: filepath.Join(t.TempDir(), "pending.json"),
+	}
+}
+
+func TestDispatcherQueuesWithinQuietHours(t *testing.T) {
+	notifier := &recordingNotifier{}
+	d := newQuietDispatcher(t, notifier)
+	changes := []lectigo.Change{{Type: lectigo.ChangeCancelled, Module: lectigo.Module{Id: "1"}}}
+
+	err := d.notify(context.Background(), "anna", changes, duringQuiet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.sent) != 0 {
+		t.Fatalf("sent %v notifications within the quiet hours", len(notifier.sent))
+	}
+
+	err = d.notify(context.Background(), "anna", nil, afterQuiet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.sent) != 1 || len(notifier.sent[0]) != 1 || notifier.sent[0][0].Module.Id != "1" {
+		t.Fatalf("sent = %+v, want the queued change", notifier.sent)
+	}
+
+	// The queued change is only sent once
+	err = d.notify(context.Background(), "anna", nil, afterQuiet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.sent) != 1 {
+		t.Errorf("sent %v notifications, want 1", len(notifier.sent))
+	}
+}
+
+func TestDispatcherQueuesByAccount(t *testing.T) {
+	notifier := &recordingNotifier{}
+	d := newQuietDispatcher(t, notifier)
+	d.notify(context.Background(), "anna", []lectigo.Change{{Type: lectigo.ChangeNew}}, duringQuiet)
+
+	d.notify(context.Background(), "bo", nil, afterQuiet)
+	if len(notifier.sent) != 0 {
+		t.Errorf("sent the changes of anna with the changes of bo")
+	}
+}
+
+func TestDispatcherRequeuesFailedSend(t *testing.T) {
+	notifier := &recordingNotifier{err: errors.New("unreachable")}
+	d := newQuietDispatcher(t, notifier)
+	d.notify(context.Background(), "anna", []lectigo.Change{{Type: lectigo.ChangeNew, Module: lectigo.Module{Id: "1"}}}, duringQuiet)
+
+	err := d.notify(context.Background(), "anna", []lectigo.Change{{Type: lectigo.ChangeRoom, Module: lectigo.Module{Id: "2"}}}, afterQuiet)
+	if err == nil {
+		t.Fatal("the failing send returned no error")
+	}
+
+	notifier.err = nil
+	err = d.notify(context.Background(), "anna", nil, afterQuiet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.sent) != 1 || len(notifier.sent[0]) != 2 {
+		t.Fatalf("sent = %+v, want the queued and the failed change", notifier.sent)
+	}
+	if notifier.sent[0][0].Module.Id != "1" || notifier.sent[0][1].Module.Id != "2" {
+		t.Errorf("sent = %+v, want the changes in order", notifier.sent[0])
+	}
+}
+
+func TestDispatcherWithoutPendingDrops(t *testing.T) {
+	notifier := &recordingNotifier{}
+	d := newQuietDispatcher(t, notifier)
+	d.PendingPath = ""
+	d.notify(context.Background(), "", []lectigo.Change{{Type: lectigo.ChangeNew}}, duringQuiet)
+
+	d.notify(context.Background(), "", nil, afterQuiet)
+	if len(notifier.sent) != 0 {
+		t.Errorf("sent %v notifications without a pending file", len(notifier.sent))
+	}
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+)
+
+// The changes held back by quiet hours, by channel name and account
+type pendingChanges map[string]map[string][]lectigo.Change
+
+// Returns the default path of the pending notifications in the user cache directory
+func DefaultPendingPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lectigo", "notifications-pending.json"), nil
+}
+
+// Reads the pending changes from a JSON file. A missing file has no pending changes
+func loadPending(path string) (pendingChanges, error) {
+	pending := make(pendingChanges)
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return pending, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, &pending)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse pending notifications %q: %w", path, err)
+	}
+	if pending == nil {
+		pending = make(pendingChanges)
+	}
+	return pending, nil
+}
+
+// Writes the pending changes to a JSON file
+func (p pendingChanges) save(path string) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// Queues the changes of the account for the channel
+func (p pendingChanges) add(channel, account string, changes []lectigo.Change) {
+	if p[channel] == nil {
+		p[channel] = make(map[string][]lectigo.Change)
+	}
+	p[channel][account] = append(p[channel][account], changes...)
+}
+
+// Removes and returns the queued changes of the account for the channel
+func (p pendingChanges) take(channel, account string) []lectigo.Change {
+	changes := p[channel][account]
+	delete(p[channel], account)
+	if len(p[channel]) == 0 {
+		delete(p, channel)
+	}
+	return changes
+}
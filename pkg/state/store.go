@@ -0,0 +1,377 @@
+// Package state keeps a local history of syncs: every version of the scraped modules and every change made to the
+// calendar by each run
+package state
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	runsBucket    = []byte("runs")
+	modulesBucket = []byte("modules")
+	actionsBucket = []byte("actions")
+)
+
+// How long to wait for another lectigo process holding the store
+const lockTimeout = 10 * time.Second
+
+var ErrStoreInUse = errors.New("the state store is used by another lectigo process")
+
+// The local history of syncs
+type Store struct {
+	db *bolt.DB
+}
+
+// A single sync of an account
+type Run struct {
+	ID         uint64    `json:"id"`
+	Account    string    `json:"account,omitempty"`
+	CalendarID string    `json:"calendarId"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Weeks      int       `json:"weeks"`
+	Modules    int       `json:"modules"`  // The amount of scraped modules
+	Versions   int       `json:"versions"` // The amount of new module versions
+	Inserted   int       `json:"inserted"`
+	Updated    int       `json:"updated"`
+	Deleted    int       `json:"deleted"`
+	Failed     int       `json:"failed"` // The amount of calendar actions that failed
+	Error      string    `json:"error,omitempty"`
+}
+
+// A version of a module as scraped by a run. A new version is only stored when the module changed
+type ModuleVersion struct {
+	RunID     uint64         `json:"runId"`
+	ScrapedAt time.Time      `json:"scrapedAt"`
+	Module    lectigo.Module `json:"module"`
+	Hash      string         `json:"hash"`
+}
+
+// A change made to the calendar by a run, or tried and failed if Error is set
+type Action struct {
+	RunID uint64    `json:"runId"`
+	Time  time.Time `json:"time"`
+	lectigo.CalendarAction
+}
+
+// The versions of a module and the changes made to its event
+type ModuleHistory struct {
+	Account  string
+	ModuleID string
+	Versions []ModuleVersion
+	Actions  []Action
+}
+
+// Returns the latest version of the module
+func (h *ModuleHistory) Latest() *ModuleVersion {
+	return &h.Versions[len(h.Versions)-1]
+}
+
+// Returns the default path of the store in the user cache directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lectigo", "state.db"), nil
+}
+
+// Opens the store at path, creating it if it does not exist
+func Open(path string) (*Store, error) {
+	return open(path, false)
+}
+
+// Opens the existing store at path for reading
+func OpenReadOnly(path string) (*Store, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return open(path, true)
+}
+
+func open(path string, readOnly bool) (*Store, error) {
+	if !readOnly {
+		err := os.MkdirAll(filepath.Dir(path), 0700)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: lockTimeout, ReadOnly: readOnly})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, ErrStoreInUse
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !readOnly {
+		err = db.Update(func(tx *bolt.Tx) error {
+			for _, bucket := range [][]byte{runsBucket, modulesBucket, actionsBucket} {
+				if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Records a run of the account with its scraped modules and the result of the calendar update, including the calendar
+// actions that failed. The run is recorded even if it failed, with the modules scraped before the failure
+func (s *Store) RecordRun(run Run, modules map[string]lectigo.Module, result *lectigo.SyncResult, syncErr error) (*Run, error) {
+	run.FinishedAt = time.Now()
+	run.Modules = len(modules)
+	if result != nil {
+		run.Inserted, run.Updated, run.Deleted = result.Inserted, result.Updated, result.Deleted
+		run.Failed = len(result.Failures)
+	}
+	if syncErr != nil {
+		run.Error = syncErr.Error()
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		runs := tx.Bucket(runsBucket)
+		id, err := runs.NextSequence()
+		if err != nil {
+			return err
+		}
+		run.ID = id
+
+		moduleBucket := tx.Bucket(modulesBucket)
+		for moduleID, module := range modules {
+			hash, err := moduleHash(module)
+			if err != nil {
+				return err
+			}
+			prefix := moduleKeyPrefix(run.Account, moduleID)
+
+			// Only modules differing from their latest version are stored
+			latest := latestWithPrefix(moduleBucket.Cursor(), prefix)
+			if latest != nil {
+				var version ModuleVersion
+				if err := json.Unmarshal(latest, &version); err == nil && version.Hash == hash {
+					continue
+				}
+			}
+
+			value, err := json.Marshal(ModuleVersion{RunID: id, ScrapedAt: run.FinishedAt, Module: module, Hash: hash})
+			if err != nil {
+				return err
+			}
+			err = moduleBucket.Put(append(prefix, itob(id)...), value)
+			if err != nil {
+				return err
+			}
+			run.Versions++
+		}
+
+		if result != nil {
+			actionBucket := tx.Bucket(actionsBucket)
+			actions := append(slices.Clip(result.Actions), result.Failures...)
+			for i, action := range actions {
+				value, err := json.Marshal(Action{RunID: id, Time: run.FinishedAt, CalendarAction: action})
+				if err != nil {
+					return err
+				}
+				key := append(moduleKeyPrefix(run.Account, action.ModuleID), itob(id)...)
+				key = append(key, itob(uint64(i))...)
+				err = actionBucket.Put(key, value)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		value, err := json.Marshal(run)
+		if err != nil {
+			return err
+		}
+		return runs.Put(itob(id), value)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not record run: %w", err)
+	}
+	return &run, nil
+}
+
+// Returns the latest runs of every account, or only of the given account if not empty, newest first. A limit of zero
+// or less returns every run
+func (s *Store) Runs(account string, limit int) ([]Run, error) {
+	var runs []Run
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(runsBucket)
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.Last(); k != nil && (limit <= 0 || len(runs) < limit); k, v = c.Prev() {
+			var run Run
+			if err := json.Unmarshal(v, &run); err != nil {
+				return err
+			}
+			if account != "" && run.Account != account {
+				continue
+			}
+			runs = append(runs, run)
+		}
+		return nil
+	})
+	return runs, err
+}
+
+// Returns the history of a module of every account, or only of the given account if not empty
+func (s *Store) Module(account, moduleID string) ([]ModuleHistory, error) {
+	histories, err := s.histories(func(h *ModuleHistory) bool {
+		return h.ModuleID == moduleID && (account == "" || h.Account == account)
+	})
+	return histories, err
+}
+
+// Returns the history of the modules which had any version within the week, sorted by the start of their latest version
+func (s *Store) Week(account string, year, week int) ([]ModuleHistory, error) {
+	histories, err := s.histories(func(h *ModuleHistory) bool {
+		if account != "" && h.Account != account {
+			return false
+		}
+		for _, version := range h.Versions {
+			y, w := version.Module.StartDate.ISOWeek()
+			if y == year && w == week {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(histories, func(i, j int) bool {
+		return histories[i].Latest().Module.StartDate.Before(histories[j].Latest().Module.StartDate)
+	})
+	return histories, nil
+}
+
+// Returns the histories of all modules for which keep returns true
+func (s *Store) histories(keep func(h *ModuleHistory) bool) ([]ModuleHistory, error) {
+	var histories []ModuleHistory
+	err := s.db.View(func(tx *bolt.Tx) error {
+		modules, actions := tx.Bucket(modulesBucket), tx.Bucket(actionsBucket)
+		if modules == nil {
+			return nil
+		}
+
+		// Versions are sorted by account, module and run, so the versions of a module are next to each other
+		var current *ModuleHistory
+		flush := func() {
+			if current != nil && keep(current) {
+				current.Actions = moduleActions(actions, current.Account, current.ModuleID)
+				histories = append(histories, *current)
+			}
+		}
+
+		c := modules.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			account, moduleID, ok := splitModuleKey(k)
+			if !ok {
+				continue
+			}
+			var version ModuleVersion
+			if err := json.Unmarshal(v, &version); err != nil {
+				return err
+			}
+
+			if current == nil || current.Account != account || current.ModuleID != moduleID {
+				flush()
+				current = &ModuleHistory{Account: account, ModuleID: moduleID}
+			}
+			current.Versions = append(current.Versions, version)
+		}
+		flush()
+		return nil
+	})
+	return histories, err
+}
+
+// Returns the calendar actions of the module
+func moduleActions(bucket *bolt.Bucket, account, moduleID string) []Action {
+	if bucket == nil {
+		return nil
+	}
+	var actions []Action
+	prefix := moduleKeyPrefix(account, moduleID)
+	c := bucket.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		var action Action
+		if err := json.Unmarshal(v, &action); err == nil {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// Returns the value of the last key with the prefix
+func latestWithPrefix(c *bolt.Cursor, prefix []byte) []byte {
+	var latest []byte
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		latest = v
+	}
+	return latest
+}
+
+// Returns the key prefix of the versions and actions of a module. Keys are the account, a zero byte, the module ID, a
+// zero byte and the big-endian run ID, so they sort by module and then by run
+func moduleKeyPrefix(account, moduleID string) []byte {
+	key := make([]byte, 0, len(account)+len(moduleID)+2+8)
+	key = append(key, account...)
+	key = append(key, 0)
+	key = append(key, moduleID...)
+	return append(key, 0)
+}
+
+func splitModuleKey(key []byte) (account, moduleID string, ok bool) {
+	parts := bytes.SplitN(key, []byte{0}, 3)
+	if len(parts) != 3 {
+		return "", "", false
+	}
+	return string(parts[0]), string(parts[1]), true
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+// Returns a hash of the module identifying its version
+func moduleHash(m lectigo.Module) (string, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(b)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,175 @@
+package state
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattismoel/lectigo/pkg/lectigo"
+)
+
+var testStart = time.Date(2026, 10, 19, 8, 15, 0, 0, time.UTC) // Monday of week 43
+
+func testModule(id, room string, start time.Time) lectigo.Module {
+	return lectigo.Module{Id: id, Title: "Matematik", StartDate: start, EndDate: start.Add(45 * time.Minute), Location: room}
+}
+
+func openTestStore(t *testing.T) *Store {
+	store, err := Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func recordRun(t *testing.T, store *Store, account string, modules map[string]lectigo.Module, result *lectigo.SyncResult) *Run {
+	run, err := store.RecordRun(Run{Account: account, StartedAt: time.Now()}, modules, result, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return run
+}
+
+func TestRecordRunStoresChangedVersions(t *testing.T) {
+	store := openTestStore(t)
+	module := testModule("123", "1.23", testStart)
+
+	first := recordRun(t, store, "anna", map[string]lectigo.Module{"123": module}, nil)
+	second := recordRun(t, store, "anna", map[string]lectigo.Module{"123": module}, nil)
+	module.Location = "2.01"
+	third := recordRun(t, store, "anna", map[string]lectigo.Module{"123": module}, nil)
+
+	if first.Versions != 1 || second.Versions != 0 || third.Versions != 1 {
+		t.Errorf("versions = %v, %v, %v, want 1, 0, 1", first.Versions, second.Versions, third.Versions)
+	}
+
+	histories, err := store.Module("anna", "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(histories) != 1 || len(histories[0].Versions) != 2 {
+		t.Fatalf("histories = %+v, want one module with two versions", histories)
+	}
+	versions := histories[0].Versions
+	if versions[0].RunID != first.ID || versions[1].RunID != third.ID || histories[0].Latest().Module.Location != "2.01" {
+		t.Errorf("versions = %+v, want the versions of the first and third run", versions)
+	}
+}
+
+func TestRecordRunStoresActionsAndFailures(t *testing.T) {
+	store := openTestStore(t)
+	result := &lectigo.SyncResult{
+		Inserted: 1,
+		Actions:  []lectigo.CalendarAction{{Type: lectigo.ActionInsert, ModuleID: "123", EventID: "a"}},
+		Failures: []lectigo.CalendarAction{{Type: lectigo.ActionUpdate, ModuleID: "123", EventID: "a", Error: "rate limited"}},
+	}
+	run := recordRun(t, store, "anna", map[string]lectigo.Module{"123": testModule("123", "1.23", testStart)}, result)
+	if run.Inserted != 1 || run.Failed != 1 {
+		t.Errorf("inserted and failed = %v, %v, want 1, 1", run.Inserted, run.Failed)
+	}
+
+	histories, err := store.Module("anna", "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(histories) != 1 || len(histories[0].Actions) != 2 {
+		t.Fatalf("histories = %+v, want one module with two actions", histories)
+	}
+	actions := histories[0].Actions
+	if actions[0].Type != lectigo.ActionInsert || actions[0].Error != "" {
+		t.Errorf("first action = %+v, want the insert", actions[0])
+	}
+	if actions[1].Type != lectigo.ActionUpdate || actions[1].Error != "rate limited" {
+		t.Errorf("second action = %+v, want the failed update", actions[1])
+	}
+}
+
+func TestModuleLookupIsolatesPrefixes(t *testing.T) {
+	store := openTestStore(t)
+	recordRun(t, store, "anna", map[string]lectigo.Module{
+		"123":  testModule("123", "1.23", testStart),
+		"1234": testModule("1234", "2.01", testStart),
+	}, &lectigo.SyncResult{Actions: []lectigo.CalendarAction{{Type: lectigo.ActionInsert, ModuleID: "1234"}}})
+	recordRun(t, store, "anna1", map[string]lectigo.Module{"23": testModule("23", "3.45", testStart)}, nil)
+
+	histories, err := store.Module("anna", "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(histories) != 1 || histories[0].ModuleID != "123" || len(histories[0].Versions) != 1 {
+		t.Fatalf("histories = %+v, want only module 123", histories)
+	}
+	if len(histories[0].Actions) != 0 {
+		t.Errorf("module 123 has the actions %+v of module 1234", histories[0].Actions)
+	}
+
+	// The account "anna1" and module "23" must not be taken for account "anna" and module "123"
+	histories, err = store.Module("", "23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(histories) != 1 || histories[0].Account != "anna1" {
+		t.Errorf("histories = %+v, want only module 23 of anna1", histories)
+	}
+}
+
+func TestWeek(t *testing.T) {
+	store := openTestStore(t)
+	recordRun(t, store, "anna", map[string]lectigo.Module{
+		"2": testModule("2", "1.23", testStart.Add(2*time.Hour)),
+		"1": testModule("1", "1.23", testStart),
+		"3": testModule("3", "1.23", testStart.AddDate(0, 0, 7)),
+	}, nil)
+	recordRun(t, store, "bo", map[string]lectigo.Module{"4": testModule("4", "1.23", testStart)}, nil)
+
+	histories, err := store.Week("anna", 2026, 43)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(histories) != 2 || histories[0].ModuleID != "1" || histories[1].ModuleID != "2" {
+		t.Errorf("histories = %+v, want modules 1 and 2 by start", histories)
+	}
+
+	histories, err = store.Week("", 2026, 43)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(histories) != 3 {
+		t.Errorf("got %v histories of every account, want 3", len(histories))
+	}
+}
+
+func TestRuns(t *testing.T) {
+	store := openTestStore(t)
+	for _, account := range []string{"anna", "bo", "bo", "bo", "anna"} {
+		recordRun(t, store, account, nil, nil)
+	}
+
+	runs, err := store.Runs("anna", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].ID != 5 || runs[1].ID != 1 {
+		t.Errorf("runs = %+v, want runs 5 and 1", runs)
+	}
+
+	runs, err = store.Runs("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 5 {
+		t.Errorf("got %v runs, want 5", len(runs))
+	}
+}
+
+func TestOpenReadOnlyMissing(t *testing.T) {
+	_, err := OpenReadOnly(filepath.Join(t.TempDir(), "state.db"))
+	if err == nil {
+		t.Fatal("opening a missing store succeeded")
+	}
+	if errors.Is(err, ErrStoreInUse) {
+		t.Errorf("err = %v, want a missing file error", err)
+	}
+}
@@ -0,0 +1,165 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decides when the next periodic sync happens
+type Schedule interface {
+	// Returns the first time after t at which to run
+	Next(t time.Time) time.Time
+}
+
+// Runs at a fixed interval
+type IntervalSchedule time.Duration
+
+func (s IntervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// Runs at the earliest time of any of the schedules
+type MultiSchedule []Schedule
+
+func (s MultiSchedule) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, schedule := range s {
+		n := schedule.Next(t)
+		if next.IsZero() || (!n.IsZero() && n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
+
+// Runs at the times matching a standard five field cron expression (minute, hour, day of month, month, day of week)
+type CronSchedule struct {
+	minutes  [60]bool
+	hours    [24]bool
+	days     [32]bool
+	months   [13]bool
+	weekdays [7]bool
+	anyDay   bool // The day of month field is *
+	anyWeek  bool // The day of week field is *
+}
+
+// Parses a cron expression like "*/15 7-8 * * 1-5". Fields support *, lists, ranges and steps, and the day of week is
+// 0-7 where both 0 and 7 are Sunday
+func ParseCron(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, not %v", expr, len(fields))
+	}
+
+	c := &CronSchedule{
+		anyDay:  fields[2] == "*",
+		anyWeek: fields[4] == "*",
+	}
+	weekdays := make([]bool, 8)
+	targets := []struct {
+		name     string
+		set      []bool
+		min, max int
+	}{
+		{"minute", c.minutes[:], 0, 59},
+		{"hour", c.hours[:], 0, 23},
+		{"day of month", c.days[:], 1, 31},
+		{"month", c.months[:], 1, 12},
+		{"day of week", weekdays, 0, 7},
+	}
+	for i, target := range targets {
+		err := parseCronField(fields[i], target.set, target.min, target.max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in cron expression %q: %w", target.name, expr, err)
+		}
+	}
+
+	copy(c.weekdays[:], weekdays[:7])
+	if weekdays[7] {
+		c.weekdays[time.Sunday] = true
+	}
+	return c, nil
+}
+
+// Marks the values of a single cron field in set
+func parseCronField(field string, set []bool, min, max int) error {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		start, end := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			start, err = strconv.Atoi(from)
+			if err != nil {
+				return fmt.Errorf("invalid value %q", from)
+			}
+			end = start
+			if isRange {
+				end, err = strconv.Atoi(to)
+				if err != nil {
+					return fmt.Errorf("invalid value %q", to)
+				}
+			} else if hasStep {
+				// "5/10" means from 5 to the end in steps of 10
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return fmt.Errorf("%q is outside of %v-%v", part, min, max)
+		}
+
+		for v := start; v <= end; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
+
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Expressions like "0 0 30 2 *" never match, so the search is limited
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		switch {
+		case !c.months[month]:
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+		case !c.hours[t.Hour()]:
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.minutes[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Reports whether the date of t matches. As in cron, a date matches either day field when both are restricted
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	day, weekday := c.days[t.Day()], c.weekdays[t.Weekday()]
+	switch {
+	case c.anyDay && c.anyWeek:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeek:
+		return day
+	}
+	return day || weekday
+}
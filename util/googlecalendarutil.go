@@ -4,7 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
@@ -12,40 +12,40 @@ import (
 	"golang.org/x/oauth2"
 )
 
-// Rerturns a Lectio status based on the color id of a Google Calendar event
-func StatusFromColorID(colorId string) string {
-	switch colorId {
-	case "4":
-		return "Aflyst!"
-	case "2":
-		return "Ændret!"
-	}
-	return ""
+// Keeps the Google OAuth token between runs
+type TokenStore interface {
+	LoadToken() (*oauth2.Token, error)
+	SaveToken(token *oauth2.Token) error
 }
 
-// Returns a Google Calendar color ID from a Lectio module status
-// Aflyst: "4" - red
-// Ændret: "2" - green
-// Default "" - default calendar color
-func ColorIDFromStatus(status string) string {
-	switch status {
-	case "Aflyst!":
-		return "4"
-	case "Ændret!":
-		return "2"
-	}
-	return ""
+// Stores the Google OAuth token in a JSON file
+type FileTokenStore string
+
+func (path FileTokenStore) LoadToken() (*oauth2.Token, error) {
+	return tokenFromFile(string(path))
+}
+
+func (path FileTokenStore) SaveToken(token *oauth2.Token) error {
+	return saveToken(string(path), token)
 }
 
 // Returns the HTTP client from a token.json file, if present
 func GetClient(config *oauth2.Config, tokenPath string) (*http.Client, error) {
-	token, err := tokenFromFile(tokenPath)
+	return GetClientFromStore(config, FileTokenStore(tokenPath))
+}
+
+// Returns the HTTP client from the token of the store. If the store has no token, the user is asked to log in and the new token is saved to the store
+func GetClientFromStore(config *oauth2.Config, store TokenStore) (*http.Client, error) {
+	token, err := store.LoadToken()
 	if err != nil {
 		token, err = getTokenFromWeb(config)
 		if err != nil {
 			return nil, err
 		}
-		saveToken(tokenPath, token)
+		err = store.SaveToken(token)
+		if err != nil {
+			return nil, fmt.Errorf("could not save token: %w", err)
+		}
 	}
 	return config.Client(context.Background(), token), nil
 }
@@ -63,7 +63,8 @@ func getTokenFromWeb(config *oauth2.Config) (*oauth2.Token, error) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		if err := server.Shutdown(ctx); err != nil {
-			log.Fatalf("could not shut down server: %v\n", err)
+			slog.Error("Could not shut down OAuth server", "error", err)
+			os.Exit(1)
 		}
 
 	})
@@ -93,7 +94,7 @@ func tokenFromFile(file string) (*oauth2.Token, error) {
 }
 
 func saveToken(path string, token *oauth2.Token) error {
-	fmt.Printf("Saving credential file to: %s\n", path)
+	slog.Info("Saving Google OAuth token", "path", path)
 	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
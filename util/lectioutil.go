@@ -63,12 +63,12 @@ func ConvertTimestamp(date *time.Time, stamp string) (time.Time, time.Duration,
 func ParseTimeAndDate(moduleElement string, re *regexp.Regexp) (time.Time, time.Time, error) {
 	var dateSlice []int
 
-	// Format: DD/MM-YYYY HH:MM til HH:MM
+	// Format: DD/MM-YYYY HH:MM til HH:MM (or "to" in the English UI)
 	datesAndTimesSplit := re.Split(moduleElement, -1)
 
 	for i, date := range datesAndTimesSplit {
 		if i == 5 {
-			continue // Ignore word "til"
+			continue // Ignore word "til" or "to"
 		}
 		int, err := strconv.Atoi(date)
 		if err != nil {
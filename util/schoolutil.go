@@ -0,0 +1,258 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gocolly/colly"
+	"golang.org/x/text/unicode/norm"
+)
+
+// How long the cached list of schools is used before it is fetched again
+const SchoolCacheMaxAge = 30 * 24 * time.Hour
+
+// A school registered at Lectio
+type School struct {
+	SchoolID string `json:"schoolID" yaml:"schoolID" xml:"schoolID"`
+	Name     string `json:"name" yaml:"name" xml:"name"`
+}
+
+// The cached list of schools
+type schoolCache struct {
+	FetchedAt time.Time `json:"fetchedAt"`
+	Schools   []School  `json:"schools"`
+}
+
+// Scrapes the schools registered at Lectio from the school list of the login page
+func FetchSchools(userAgent string) ([]School, error) {
+	baseURL := "https://lectio.dk/lectio/login_list.aspx"
+	c := colly.NewCollector()
+	if userAgent != "" {
+		c.UserAgent = userAgent
+	}
+
+	re := regexp.MustCompile(`/lectio/(\d+)/default.aspx`)
+	var schools []School
+
+	c.OnHTML(".buttonHeader>a[href]", func(h *colly.HTMLElement) {
+		link := h.Attr("href")
+		if !strings.Contains(link, "/default.aspx") {
+			return
+		}
+
+		// Lectio uses en dashes in some school names
+		schoolName := strings.ReplaceAll(h.Text, "–", "-")
+
+		matches := re.FindStringSubmatch(link)
+		if len(matches) == 2 {
+			schools = append(schools, School{
+				SchoolID: matches[1],
+				Name:     strings.TrimSpace(schoolName),
+			})
+		}
+	})
+
+	err := c.Visit(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(schools) == 0 {
+		return nil, errors.New("found no schools on the Lectio school list")
+	}
+	return schools, nil
+}
+
+// Returns the default path of the cached list of schools in the user cache directory
+func DefaultSchoolCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lectigo", "schools.json"), nil
+}
+
+// Returns the schools registered at Lectio from the cache at cachePath. The list is fetched from Lectio and cached
+// if the cache is missing, older than SchoolCacheMaxAge or refresh is set
+func LoadSchools(cachePath string, refresh bool, userAgent string) ([]School, error) {
+	if !refresh {
+		b, err := os.ReadFile(cachePath)
+		if err == nil {
+			cache := &schoolCache{}
+			if json.Unmarshal(b, cache) == nil && time.Since(cache.FetchedAt) < SchoolCacheMaxAge && len(cache.Schools) > 0 {
+				return cache.Schools, nil
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	schools, err := FetchSchools(userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(&schoolCache{FetchedAt: time.Now(), Schools: schools})
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(filepath.Dir(cachePath), 0755)
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(cachePath, b, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return schools, nil
+}
+
+// A school matching a search, with its score. Higher scores are better matches
+type SchoolMatch struct {
+	School
+	Score int `json:"score" yaml:"score" xml:"score"`
+}
+
+// Searches the schools for the query. Matching ignores case and diacritics (eg. "aarhus" matches "Århus"), and
+// tolerates small typos in the words of the query. The matches are sorted by score
+func SearchSchools(schools []School, query string) []SchoolMatch {
+	q := normalizeSchoolName(query)
+	queryWords := strings.Fields(q)
+	if len(queryWords) == 0 {
+		return nil
+	}
+
+	var matches []SchoolMatch
+	for _, school := range schools {
+		if score := schoolScore(normalizeSchoolName(school.Name), q, queryWords); score > 0 {
+			matches = append(matches, SchoolMatch{School: school, Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	return matches
+}
+
+// Returns the ID of the school given either by its ID or by its name. A name must match a single school best
+func FindSchoolID(schools []School, idOrName string) (string, error) {
+	if isDigits(idOrName) {
+		return idOrName, nil
+	}
+
+	matches := SearchSchools(schools, idOrName)
+	switch {
+	case len(matches) == 0:
+		return "", fmt.Errorf("found no school matching %q", idOrName)
+	case len(matches) == 1 || matches[0].Score > matches[1].Score:
+		return matches[0].SchoolID, nil
+	}
+
+	var candidates []string
+	for _, match := range matches {
+		if match.Score != matches[0].Score || len(candidates) == 5 {
+			break
+		}
+		candidates = append(candidates, fmt.Sprintf("%s (%s)", match.Name, match.SchoolID))
+	}
+	return "", fmt.Errorf("%q matches several schools, eg. %s. Use the school ID instead", idOrName, strings.Join(candidates, ", "))
+}
+
+// Scores how well the normalized name matches the normalized query. Zero means no match
+func schoolScore(name, query string, queryWords []string) int {
+	switch {
+	case name == query:
+		return 100
+	case strings.HasPrefix(name, query):
+		return 90
+	case strings.Contains(name, query):
+		return 80
+	}
+
+	// Every word of the query must match a word of the name, either by prefix or with a single typo
+	nameWords := strings.Fields(name)
+	score := 60
+	for _, queryWord := range queryWords {
+		best := 0
+		for _, nameWord := range nameWords {
+			switch {
+			case strings.HasPrefix(nameWord, queryWord):
+				best = max(best, 2)
+			case len(queryWord) >= 4 && levenshtein(queryWord, nameWord) <= 1:
+				best = max(best, 1)
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		if best == 1 {
+			score -= 10
+		}
+	}
+	return max(score, 1)
+}
+
+// Lowercases the name, spells out the Danish letters and removes diacritics and punctuation
+func normalizeSchoolName(s string) string {
+	s = strings.ToLower(s)
+	s = strings.NewReplacer("æ", "ae", "ø", "oe", "å", "aa").Replace(s)
+
+	var sb strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Drop combining marks, leaving the base letter (eg. "é" becomes "e")
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// Returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,230 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/oauth2"
+)
+
+// The environment variable holding the passphrase of the secret store
+const SecretsPassphraseEnv = "LECTIGO_SECRETS_PASSPHRASE"
+
+// The scrypt parameters used to derive the key of the secret store from its passphrase
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	secretKeyLen = 32
+)
+
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted secret store")
+
+// A local file of named secrets encrypted with AES-GCM using a key derived from a passphrase
+type SecretStore struct {
+	path    string
+	salt    []byte
+	key     []byte
+	secrets map[string]string
+	mu      sync.Mutex
+}
+
+// The contents of the secret store file
+type secretStoreFile struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"` // The encrypted secrets
+}
+
+// Returns the default path of the secret store in the user config directory
+func DefaultSecretStorePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lectigo", "secrets.enc"), nil
+}
+
+// Reports whether a secret store exists at path
+func SecretStoreExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Opens the secret store at path and decrypts it with the passphrase. If no store exists, an empty store is returned, which is created on Save
+func OpenSecretStore(path string, passphrase []byte) (*SecretStore, error) {
+	store := &SecretStore{path: path, secrets: make(map[string]string)}
+
+	bytes, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		store.salt = make([]byte, 16)
+		if _, err := rand.Read(store.salt); err != nil {
+			return nil, fmt.Errorf("could not generate salt: %w", err)
+		}
+		store.key, err = deriveSecretKey(passphrase, store.salt)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file := secretStoreFile{}
+	err = json.Unmarshal(bytes, &file)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse secret store %q: %w", path, err)
+	}
+
+	store.salt = file.Salt
+	store.key, err = deriveSecretKey(passphrase, file.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(store.key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	err = json.Unmarshal(plain, &store.secrets)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse secrets: %w", err)
+	}
+	return store, nil
+}
+
+// Returns the secret with the given name
+func (s *SecretStore) Get(name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.secrets[name]
+	return value, ok
+}
+
+// Sets the secret with the given name. The store must be saved afterwards
+func (s *SecretStore) Set(name, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets[name] = value
+}
+
+// Deletes the secret with the given name and reports whether it existed. The store must be saved afterwards
+func (s *SecretStore) Delete(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.secrets[name]
+	delete(s.secrets, name)
+	return ok
+}
+
+// Returns the sorted names of the secrets
+func (s *SecretStore) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.secrets))
+	for name := range s.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Encrypts the secrets and writes them to the store file, readable only by the user
+func (s *SecretStore) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plain, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("could not generate nonce: %w", err)
+	}
+
+	bytes, err := json.Marshal(secretStoreFile{
+		Salt:  s.salt,
+		Nonce: nonce,
+		Data:  gcm.Seal(nil, nonce, plain, nil),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0700)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(s.path, bytes, 0600)
+	if err != nil {
+		return err
+	}
+	// WriteFile keeps the permissions of an existing file
+	return os.Chmod(s.path, 0600)
+}
+
+// Derives the key of the secret store from the passphrase
+func deriveSecretKey(passphrase, salt []byte) ([]byte, error) {
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, secretKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("could not derive key: %w", err)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Stores a Google OAuth token as a secret of a secret store
+type SecretTokenStore struct {
+	Store *SecretStore
+	Name  string // The name of the secret holding the token
+}
+
+func (t *SecretTokenStore) LoadToken() (*oauth2.Token, error) {
+	value, ok := t.Store.Get(t.Name)
+	if !ok {
+		return nil, fmt.Errorf("no token named %q in secret store", t.Name)
+	}
+	token := &oauth2.Token{}
+	err := json.Unmarshal([]byte(value), token)
+	return token, err
+}
+
+func (t *SecretTokenStore) SaveToken(token *oauth2.Token) error {
+	slog.Info("Saving Google OAuth token to secret store", "name", t.Name)
+	bytes, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	t.Store.Set(t.Name, string(bytes))
+	return t.Store.Save()
+}
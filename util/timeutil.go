@@ -21,3 +21,32 @@ func GetMonday() (time.Time, error) {
 	return time.Date(t.Year(), t.Month(), t.Day()-off, 0, 0, 0, 0, location), nil
 }
 
+// Parses the dates of an all-day module element.
+// Format: DD/MM-YYYY Hele dagen or DD/MM-YYYY til DD/MM-YYYY (or "All day" and "to" in the English UI).
+// The returned end date is exclusive
+func ParseAllDayDates(matches []string) (time.Time, time.Time, error) {
+	location, _ := time.LoadLocation("Europe/Copenhagen")
+
+	startDate, err := time.ParseInLocation("2/1-2006", matches[1], location)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	endDate := startDate
+	if len(matches) > 2 && matches[2] != "" {
+		endDate, err = time.ParseInLocation("2/1-2006", matches[2], location)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+
+	return startDate, endDate.AddDate(0, 0, 1), nil
+}
+
+// Returns the Monday starting the ISO week of the year in the given location
+func ISOWeekStart(year, week int, location *time.Location) time.Time {
+	// January 4th is always in the first ISO week
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, location)
+	offset := (int(jan4.Weekday()) + 6) % 7
+	return jan4.AddDate(0, 0, -offset+(week-1)*7)
+}